@@ -0,0 +1,57 @@
+package syncmap
+
+import "fmt"
+
+// EventOp is the operation of a change Event.
+type EventOp uint8
+
+const (
+	EventSet EventOp = iota + 1
+	EventDelete
+)
+
+// Event is a change to apply to a map, such as one read from a replication
+// change feed. Seq numbers start at 1 and increase by one per event.
+type Event[K comparable, V any] struct {
+	Seq   uint64
+	Op    EventOp
+	Key   K
+	Value V // unset for EventDelete
+}
+
+// ApplyBatch applies events in order on a follower map. Events whose Seq was
+// already applied are skipped, so a batch can be retried safely after a
+// failure. If an event's Seq is more than one past the last applied one,
+// ApplyBatch stops and returns an error wrapping ErrSequenceGap.
+// Returns the number of events applied by this call.
+func (m *Map[K, V]) ApplyBatch(events []Event[K, V]) (applied int, err error) {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	last := m.appliedSeq.Load()
+	for _, e := range events {
+		if e.Seq <= last {
+			continue
+		}
+		if e.Seq != last+1 {
+			return applied, fmt.Errorf("%w: expected seq %d, got %d", ErrSequenceGap, last+1, e.Seq)
+		}
+		switch e.Op {
+		case EventSet:
+			m.Set(e.Key, e.Value)
+		case EventDelete:
+			m.Delete(e.Key)
+		default:
+			return applied, fmt.Errorf("%w: unknown event op %d at seq %d", ErrValidation, e.Op, e.Seq)
+		}
+		last = e.Seq
+		m.appliedSeq.Store(last)
+		applied++
+	}
+	return applied, nil
+}
+
+// Returns the Seq of the last event applied by ApplyBatch, 0 if none
+func (m *Map[K, V]) AppliedSeq() uint64 {
+	return m.appliedSeq.Load()
+}
@@ -0,0 +1,51 @@
+package syncmap
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_ApplyBatch(t *testing.T) {
+	m := New64()
+	batch := []Event[uint64, interface{}]{
+		{Seq: 1, Op: EventSet, Key: 1, Value: 1},
+		{Seq: 2, Op: EventSet, Key: 2, Value: 2},
+		{Seq: 3, Op: EventDelete, Key: 1},
+	}
+
+	applied, err := m.ApplyBatch(batch)
+	if err != nil || applied != 3 {
+		t.Error("ApplyBatch should apply every event", applied, err)
+	}
+	if m.Has(1) || !m.Has(2) || m.AppliedSeq() != 3 {
+		t.Error("ApplyBatch should apply the events in order")
+	}
+
+	// Retrying an overlapping batch applies only the new events.
+	applied, err = m.ApplyBatch(append(batch, Event[uint64, interface{}]{Seq: 4, Op: EventSet, Key: 1, Value: 10}))
+	if err != nil || applied != 1 {
+		t.Error("ApplyBatch should skip already applied events", applied, err)
+	}
+	if v, _ := m.Get(1); v.(int) != 10 {
+		t.Error("ApplyBatch should apply the new event")
+	}
+}
+
+func Test_ApplyBatchGap(t *testing.T) {
+	m := New64()
+	applied, err := m.ApplyBatch([]Event[uint64, interface{}]{
+		{Seq: 1, Op: EventSet, Key: 1, Value: 1},
+		{Seq: 3, Op: EventSet, Key: 3, Value: 3},
+	})
+	if !errors.Is(err, ErrSequenceGap) || applied != 1 {
+		t.Error("ApplyBatch should stop at a gap", applied, err)
+	}
+	if m.Has(3) || m.AppliedSeq() != 1 {
+		t.Error("events after a gap should not be applied")
+	}
+
+	_, err = m.ApplyBatch([]Event[uint64, interface{}]{{Seq: 2, Key: 2}})
+	if !errors.Is(err, ErrValidation) {
+		t.Error("ApplyBatch should reject unknown operations", err)
+	}
+}
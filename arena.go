@@ -0,0 +1,154 @@
+package syncmap
+
+import "sync"
+
+// arenaShard stores the values of a shard inline in a slice, addressed by
+// the index kept in slots. values and keys are parallel and always dense:
+// deleting an entry relocates the last one into its place.
+type arenaShard[K comparable, V any] struct {
+	slots  map[K]int32
+	keys   []K
+	values []V
+	sync.RWMutex
+}
+
+// ArenaMap is a thread safe map that stores values inline in one slice per
+// shard instead of in the buckets of a built-in map. For fixed-size values
+// without pointers, such as small structs of numbers, the garbage collector
+// never has to scan the values, and reading one costs no pointer chasing.
+// The backing slices shrink when occupancy drops below a quarter of their
+// capacity.
+//
+// Pointers to values cannot be kept: values move when other entries are
+// deleted or the arena is compacted. ArenaMap has none of the TTL, index or
+// migration features of Map.
+type ArenaMap[K comparable, V any] struct {
+	shardCount uint32
+	shards     []*arenaShard[K, V]
+	hash       func(key K) uint32
+}
+
+// Create a new ArenaMap with given shard count.
+// NOTE: shard count must be power of 2, default shard count will be used otherwise.
+func NewArenaMap[K comparable, V any](shardCount uint32) *ArenaMap[K, V] {
+	if !isPowerOfTwo(shardCount) {
+		shardCount = defaultShardCount
+	}
+	m := &ArenaMap[K, V]{
+		shardCount: shardCount,
+		shards:     make([]*arenaShard[K, V], shardCount),
+		hash:       defaultHasher[K](),
+	}
+	for i := range m.shards {
+		m.shards[i] = &arenaShard[K, V]{slots: make(map[K]int32)}
+	}
+	return m
+}
+
+// Find the specific shard with the given key
+func (m *ArenaMap[K, V]) locate(key K) *arenaShard[K, V] {
+	return m.shards[m.hash(key)&(m.shardCount-1)]
+}
+
+// Retrieves a value
+func (m *ArenaMap[K, V]) Get(key K) (value V, ok bool) {
+	shard := m.locate(key)
+	shard.RLock()
+	if i, has := shard.slots[key]; has {
+		value, ok = shard.values[i], true
+	}
+	shard.RUnlock()
+	return
+}
+
+// Sets value with the given key
+func (m *ArenaMap[K, V]) Set(key K, value V) {
+	shard := m.locate(key)
+	shard.Lock()
+	if i, ok := shard.slots[key]; ok {
+		shard.values[i] = value
+	} else {
+		shard.slots[key] = int32(len(shard.values))
+		shard.keys = append(shard.keys, key)
+		shard.values = append(shard.values, value)
+	}
+	shard.Unlock()
+}
+
+// Removes an item
+func (m *ArenaMap[K, V]) Delete(key K) {
+	shard := m.locate(key)
+	shard.Lock()
+	if i, ok := shard.slots[key]; ok {
+		shard.free(key, i)
+	}
+	shard.Unlock()
+}
+
+// Frees slot i of key by relocating the last entry into it, must be called
+// with the write lock held
+func (shard *arenaShard[K, V]) free(key K, i int32) {
+	last := int32(len(shard.values) - 1)
+	if i != last {
+		shard.keys[i] = shard.keys[last]
+		shard.values[i] = shard.values[last]
+		shard.slots[shard.keys[i]] = i
+	}
+	var zero V
+	shard.values[last] = zero
+	shard.keys = shard.keys[:last]
+	shard.values = shard.values[:last]
+	delete(shard.slots, key)
+	shard.compact()
+}
+
+// Shrinks the backing slices once they are less than a quarter full
+func (shard *arenaShard[K, V]) compact() {
+	n := len(shard.values)
+	if cap(shard.values) < 64 || n >= cap(shard.values)/4 {
+		return
+	}
+	keys := make([]K, n, 2*n)
+	copy(keys, shard.keys)
+	values := make([]V, n, 2*n)
+	copy(values, shard.values)
+	shard.keys, shard.values = keys, values
+	// Rebuild the built-in map too, it never shrinks on its own.
+	slots := make(map[K]int32, n)
+	for i, key := range shard.keys {
+		slots[key] = int32(i)
+	}
+	shard.slots = slots
+}
+
+// Whether ArenaMap has the given key
+func (m *ArenaMap[K, V]) Has(key K) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Returns the number of items
+func (m *ArenaMap[K, V]) Size() int {
+	size := 0
+	for _, shard := range m.shards {
+		shard.RLock()
+		size += len(shard.values)
+		shard.RUnlock()
+	}
+	return size
+}
+
+// Calls fn for each item, one shard at a time under its read lock, until fn
+// returns false. fn must not call methods of the map.
+func (m *ArenaMap[K, V]) Range(fn func(key K, value V) bool) {
+	for _, shard := range m.shards {
+		shard.RLock()
+		for i, key := range shard.keys {
+			if !fn(key, shard.values[i]) {
+				shard.RUnlock()
+				return
+			}
+		}
+		shard.RUnlock()
+	}
+}
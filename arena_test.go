@@ -0,0 +1,47 @@
+package syncmap
+
+import "testing"
+
+type point struct {
+	x, y float64
+}
+
+func Test_ArenaMap(t *testing.T) {
+	m := NewArenaMap[uint64, point](4)
+	for i := 0; i < 1000; i++ {
+		m.Set(uint64(i), point{float64(i), 0})
+	}
+	m.Set(7, point{7, 7})
+	if v, ok := m.Get(7); !ok || v.y != 7 {
+		t.Error("Set should overwrite an existing value", v)
+	}
+	if m.Size() != 1000 {
+		t.Error("map should have 1000 items", m.Size())
+	}
+
+	for i := 0; i < 990; i++ {
+		m.Delete(uint64(i))
+	}
+	if m.Size() != 10 || m.Has(0) {
+		t.Error("Delete should remove the items", m.Size())
+	}
+	for i := 990; i < 1000; i++ {
+		if v, ok := m.Get(uint64(i)); !ok || v.x != float64(i) {
+			t.Error("relocated values should be kept", i, v)
+		}
+	}
+	for _, shard := range m.shards {
+		if cap(shard.values) > 64 {
+			t.Error("arena should be compacted when occupancy drops", cap(shard.values))
+		}
+	}
+
+	n := 0
+	m.Range(func(key uint64, value point) bool {
+		n++
+		return key != 995
+	})
+	if n == 0 || n > 10 {
+		t.Error("Range should stop when fn returns false", n)
+	}
+}
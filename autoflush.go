@@ -0,0 +1,101 @@
+package syncmap
+
+import (
+	"sync"
+	"time"
+)
+
+// AutoFlush empties a map at a fixed interval and hands the removed entries
+// to a callback, as returned by WithAutoFlush.
+type AutoFlush struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// WithAutoFlush swaps out the contents of the map every interval and calls
+// onFlush with the entries accumulated since the previous flush, the classic
+// pattern of aggregating metrics for a window and then emitting them. Every
+// shard is locked while swapping, so each window is a consistent cut: a write
+// lands in exactly one window. onFlush runs in a background goroutine
+// without any lock held and is not called for empty windows. Flushes are
+// delayed while maintenance is paused. An interval below a millisecond is
+// raised to one.
+func (m *Map[K, V]) WithAutoFlush(interval time.Duration, onFlush func([]Entry[K, V])) *AutoFlush {
+	a := &AutoFlush{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if !m.inflight.begin() {
+		close(a.done)
+		return a
+	}
+
+	flush := func() {
+		if entries := m.swapOut(); len(entries) > 0 {
+			onFlush(entries)
+		}
+	}
+	go func() {
+		defer m.inflight.end()
+		defer close(a.done)
+		ticker := time.NewTicker(clampInterval(interval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-a.stop:
+				flush()
+				return
+			}
+			select {
+			case <-m.maintenance.allowed():
+				flush()
+			case <-a.stop:
+				flush()
+				return
+			}
+		}
+	}()
+	return a
+}
+
+// Stops flushing the map, after handing the current window to the callback
+func (a *AutoFlush) Close() {
+	a.stopOnce.Do(func() { close(a.stop) })
+	<-a.done
+}
+
+// Empties every shard at once and returns the entries that were not expired
+func (m *Map[K, V]) swapOut() []Entry[K, V] {
+	shards := m.shards()
+	type window struct {
+		items   map[K]V
+		expires map[K]expiry
+	}
+	windows := make([]window, len(shards))
+	for _, shard := range shards {
+		shard.Lock()
+	}
+	for i, shard := range shards {
+		// Forks must not write in place to the maps handed out.
+		shard.own()
+		windows[i] = window{shard.items, shard.expires}
+		shard.clear()
+	}
+	for _, shard := range shards {
+		shard.Unlock()
+	}
+
+	var entries []Entry[K, V]
+	now := time.Now().UnixNano()
+	for _, w := range windows {
+		for key, value := range w.items {
+			if e, ok := w.expires[key]; ok && e.expired(now) {
+				continue
+			}
+			entries = append(entries, Entry[K, V]{key, value})
+		}
+	}
+	return entries
+}
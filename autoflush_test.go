@@ -0,0 +1,56 @@
+package syncmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_WithAutoFlush(t *testing.T) {
+	m := New64()
+	var (
+		mu      sync.Mutex
+		windows [][]Item64
+	)
+	a := m.WithAutoFlush(5*time.Millisecond, func(entries []Item64) {
+		mu.Lock()
+		windows = append(windows, entries)
+		mu.Unlock()
+	})
+
+	m.Set(1, 1)
+	m.Set(2, 2)
+	time.Sleep(20 * time.Millisecond)
+	if m.Size() != 0 {
+		t.Error("the map should be emptied by the flush", m.Size())
+	}
+	m.Set(3, 3)
+	a.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(windows) != 2 {
+		t.Fatal("empty windows should be skipped and Close should flush", len(windows))
+	}
+	if len(windows[0]) != 2 || len(windows[1]) != 1 || windows[1][0].Key != 3 {
+		t.Error("each window should hold the entries set during it", windows)
+	}
+}
+
+func Test_WithAutoFlushZeroInterval(t *testing.T) {
+	m := New64()
+	flushed := make(chan int, 1)
+	a := m.WithAutoFlush(0, func(entries []Item64) {
+		select {
+		case flushed <- len(entries):
+		default:
+		}
+	})
+	defer a.Close()
+	m.Set(1, 1)
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Error("a non-positive interval should flush every millisecond")
+	}
+}
@@ -0,0 +1,61 @@
+package syncmap
+
+import "context"
+
+// IterItemsBuffered is IterItems with a channel of the given buffer size, so
+// the producer can run ahead of a slow consumer
+func (m *Map[K, V]) IterItemsBuffered(size int) <-chan Entry[K, V] {
+	return m.iterItems(context.Background(), make(chan Entry[K, V], size), nil)
+}
+
+// IterBatches returns a channel from which all items can be read in batches
+// of up to n items, to save the cost of a channel operation per item when
+// scanning large maps. Batches are filled across shards and each batch is a
+// new slice owned by the receiver. Like IterItems, the channel must be
+// drained; use IterBatchesCtx to stop early.
+func (m *Map[K, V]) IterBatches(n int) <-chan []Entry[K, V] {
+	return m.IterBatchesCtx(context.Background(), n)
+}
+
+// IterBatchesCtx is IterBatches, except that the channel is closed as soon as
+// ctx is done, see IterKeysCtx
+func (m *Map[K, V]) IterBatchesCtx(ctx context.Context, n int) <-chan []Entry[K, V] {
+	if n <= 0 {
+		panic("syncmap: batch size must be positive")
+	}
+	ch := make(chan []Entry[K, V])
+	if !m.inflight.begin() {
+		close(ch)
+		return ch
+	}
+	go func() {
+		defer m.inflight.end()
+		defer close(ch)
+		send := func(batch []Entry[K, V]) bool {
+			select {
+			case ch <- batch:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		var items []Entry[K, V]
+		batch := make([]Entry[K, V], 0, n)
+		for _, shard := range m.shards() {
+			items = shard.appendItems(items[:0])
+			for _, item := range items {
+				batch = append(batch, item)
+				if len(batch) == n {
+					if !send(batch) {
+						return
+					}
+					batch = make([]Entry[K, V], 0, n)
+				}
+			}
+		}
+		if len(batch) > 0 {
+			send(batch)
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,53 @@
+package syncmap
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_IterBatches(t *testing.T) {
+	m := New64()
+	for i := uint64(0); i < 1000; i++ {
+		m.Set(i, i)
+	}
+	seen := make(map[uint64]bool)
+	for batch := range m.IterBatches(64) {
+		if len(batch) == 0 || len(batch) > 64 {
+			t.Error("batch size should be within 1..64", len(batch))
+		}
+		for _, item := range batch {
+			if item.Value.(uint64) != item.Key {
+				t.Error("batch item should hold its value", item)
+			}
+			seen[item.Key] = true
+		}
+	}
+	if len(seen) != 1000 {
+		t.Error("batches should hold every item once", len(seen))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := m.IterBatchesCtx(ctx, 10)
+	<-ch
+	cancel()
+	for range ch {
+	}
+}
+
+func Test_IterItemsBuffered(t *testing.T) {
+	m := New64()
+	for i := uint64(0); i < 100; i++ {
+		m.Set(i, i)
+	}
+	ch := m.IterItemsBuffered(16)
+	if cap(ch) != 16 {
+		t.Error("channel should have the requested buffer", cap(ch))
+	}
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != 100 {
+		t.Error("buffered iteration should see every item", n)
+	}
+}
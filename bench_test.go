@@ -0,0 +1,137 @@
+package syncmap
+
+import (
+	"strconv"
+	"testing"
+)
+
+// Benchmarks of the hot path. Keys are spread over many shards and the
+// values are small, so the cost of routing a key to its shard shows.
+
+const benchKeys = 1 << 16
+
+func BenchmarkLocate64(b *testing.B) {
+	m := New64()
+	for i := 0; i < b.N; i++ {
+		m.locate(uint64(i))
+	}
+}
+
+func BenchmarkLocate(b *testing.B) {
+	m := New()
+	for i := 0; i < b.N; i++ {
+		m.locate(uint32(i))
+	}
+}
+
+func BenchmarkLocateString(b *testing.B) {
+	m := NewString()
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "user:" + strconv.Itoa(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.locate(keys[i&(benchKeys-1)])
+	}
+}
+
+func BenchmarkGet64(b *testing.B) {
+	m := New64()
+	for i := 0; i < benchKeys; i++ {
+		m.Set(uint64(i), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Get(uint64(i & (benchKeys - 1)))
+	}
+}
+
+func BenchmarkGetParallel64(b *testing.B) {
+	m := New64()
+	for i := 0; i < benchKeys; i++ {
+		m.Set(uint64(i), i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Get(uint64(i & (benchKeys - 1)))
+			i++
+		}
+	})
+}
+
+func BenchmarkGetParallelLockFree64(b *testing.B) {
+	m := New64()
+	for i := 0; i < benchKeys; i++ {
+		m.Set(uint64(i), i)
+	}
+	m.WithLockFreeReads()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Get(uint64(i & (benchKeys - 1)))
+			i++
+		}
+	})
+}
+
+func BenchmarkGetParallelReadMostly64(b *testing.B) {
+	m := NewReadMostlyMap[uint64, interface{}](defaultShardCount)
+	for i := 0; i < benchKeys; i++ {
+		m.Set(uint64(i), i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Get(uint64(i & (benchKeys - 1)))
+			i++
+		}
+	})
+}
+
+func BenchmarkGetMany64(b *testing.B) {
+	m := New64()
+	keys := make([]uint64, 128)
+	for i := range keys {
+		m.Set(uint64(i), i)
+		keys[i] = uint64(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i += len(keys) {
+		m.GetMany(keys)
+	}
+}
+
+func BenchmarkSet64(b *testing.B) {
+	m := New64()
+	for i := 0; i < b.N; i++ {
+		m.Set(uint64(i&(benchKeys-1)), i)
+	}
+}
+
+func BenchmarkSetMany64(b *testing.B) {
+	m := New64()
+	items := make([]Item64, 1024)
+	for i := range items {
+		items[i] = Item64{Key: uint64(i), Value: i}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i += len(items) {
+		m.SetMany(items)
+	}
+}
+
+func BenchmarkSetParallel64(b *testing.B) {
+	m := New64()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Set(uint64(i&(benchKeys-1)), i)
+			i++
+		}
+	})
+}
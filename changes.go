@@ -0,0 +1,27 @@
+package syncmap
+
+// WithChangeDetection makes Set skip writes of a value that eq reports equal
+// to the current one, so producers re-setting identical values at high rate
+// cause no work: the entry keeps its expiration, and indexes and the LRU
+// order are not touched. It applies to Set, SetCtx and ApplyBatch. eq is
+// called with the shard lock held and must not call methods of the map. A
+// nil eq disables change detection. Returns the map for chaining.
+func (m *Map[K, V]) WithChangeDetection(eq func(old, new V) bool) *Map[K, V] {
+	if eq == nil {
+		m.unchanged.Store(nil)
+	} else {
+		m.unchanged.Store(&eq)
+	}
+	return m
+}
+
+// Whether writing value would not change the live entry of key, must be
+// called with the lock held
+func (m *Map[K, V]) isUnchanged(shard *shard[K, V], key K, value V) bool {
+	eq := m.unchanged.Load()
+	if eq == nil {
+		return false
+	}
+	old, ok := shard.lookup(key)
+	return ok && (*eq)(old, value)
+}
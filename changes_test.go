@@ -0,0 +1,37 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_WithChangeDetection(t *testing.T) {
+	writes := 0
+	m := New64().WithChangeDetection(func(old, new interface{}) bool {
+		return old == new
+	})
+	m.IndexBy("writes", func(v interface{}) (interface{}, bool) {
+		writes++
+		return nil, false
+	})
+
+	m.Set(1, "a")
+	m.Touch(1, time.Hour)
+	m.Set(1, "a")
+	if writes != 1 {
+		t.Error("writing an equal value should be skipped", writes)
+	}
+	if ttl, _ := m.TTL(1); ttl == 0 {
+		t.Error("skipped write should keep the expiration")
+	}
+	m.Set(1, "b")
+	if v, _ := m.Get(1); v != "b" || writes != 2 {
+		t.Error("writing a different value should not be skipped", v)
+	}
+
+	m.WithChangeDetection(nil)
+	m.Set(1, "b")
+	if writes != 3 {
+		t.Error("change detection should be disabled", writes)
+	}
+}
@@ -0,0 +1,186 @@
+// Command syncmap-dist reports how a sample of keys is distributed across
+// shards for each hasher available in syncmap.
+//
+// Keys are read one per line, as unsigned decimal integers, or as strings
+// with -strings, from the files given as arguments or from stdin when there
+// are none:
+//
+//	syncmap-dist -shards 16,32,64 keys.txt
+//	syncmap-dist -strings -shards 16,32,64 keys.txt
+//
+// The default hasher is the one maps use unless given another. For integer
+// keys it is splitmix of the key xored with a salt drawn for each map, for
+// string keys hash/maphash with a seed drawn for each map. Here the salt and
+// seed are drawn once per run, so its results vary slightly between runs.
+//
+// For every hasher and shard count it prints the shard occupancy range, the
+// chi-square statistic against a uniform distribution and the percentage of
+// keys held by the fullest shard.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DeanThompson/syncmap"
+)
+
+var (
+	// salt and seed stand for those of the default hashers, drawn for each map
+	salt = rand.Uint64()
+	seed = maphash.MakeSeed()
+)
+
+// intHashers lists the shard selection hashers of integer keys provided by
+// syncmap.
+var intHashers = map[string]func(uint64) uint32{
+	"bkdr":     syncmap.HashBKDR,
+	"default":  func(key uint64) uint32 { return syncmap.HashSplitMix(key ^ salt) },
+	"splitmix": syncmap.HashSplitMix,
+}
+
+// stringHashers lists the shard selection hashers of string keys provided
+// by syncmap.
+var stringHashers = map[string]func(string) uint32{
+	"bkdr":    syncmap.HashBKDRString,
+	"default": func(key string) uint32 { return uint32(maphash.String(seed, key)) },
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "syncmap-dist:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("syncmap-dist", flag.ContinueOnError)
+	shardsFlag := flags.String("shards", "8,16,32,64,128", "comma separated shard counts, each a power of 2")
+	stringKeys := flags.Bool("strings", false, "read keys as strings instead of unsigned integers")
+	verbose := flags.Bool("v", false, "print the number of keys in every shard")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	shardCounts, err := parseShardCounts(*shardsFlag)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	if flags.NArg() == 0 {
+		if lines, err = readLines(stdin, lines); err != nil {
+			return err
+		}
+	}
+	for _, name := range flags.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		lines, err = readLines(f, lines)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if len(lines) == 0 {
+		return errors.New("no keys read")
+	}
+
+	fmt.Fprintf(stdout, "%d keys\n", len(lines))
+	fmt.Fprintf(stdout, "%-10s %6s %8s %8s %10s %10s %8s\n", "hasher", "shards", "min", "max", "chi2", "chi2/df", "worst%")
+	if *stringKeys {
+		report(stdout, lines, stringHashers, shardCounts, *verbose)
+		return nil
+	}
+	keys := make([]uint64, len(lines))
+	for i, line := range lines {
+		if keys[i], err = strconv.ParseUint(line, 10, 64); err != nil {
+			return fmt.Errorf("invalid key %q: %v", line, err)
+		}
+	}
+	report(stdout, keys, intHashers, shardCounts, *verbose)
+	return nil
+}
+
+func parseShardCounts(s string) ([]int, error) {
+	var counts []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 2 || n&(n-1) != 0 {
+			return nil, fmt.Errorf("invalid shard count %q: must be a power of 2 greater than 1", field)
+		}
+		counts = append(counts, n)
+	}
+	return counts, nil
+}
+
+func readLines(r io.Reader, lines []string) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// Prints a line for every hasher, by name, and shard count
+func report[K any](w io.Writer, keys []K, hashers map[string]func(K) uint32, shardCounts []int, verbose bool) {
+	names := make([]string, 0, len(hashers))
+	for name := range hashers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, n := range shardCounts {
+			counts := distribute(keys, hashers[name], n)
+			reportCounts(w, name, counts, len(keys), verbose)
+		}
+	}
+}
+
+func distribute[K any](keys []K, hash func(K) uint32, shards int) []int {
+	counts := make([]int, shards)
+	mask := uint32(shards - 1)
+	for _, key := range keys {
+		counts[hash(key)&mask]++
+	}
+	return counts
+}
+
+func reportCounts(w io.Writer, name string, counts []int, total int, verbose bool) {
+	expected := float64(total) / float64(len(counts))
+	min, max := counts[0], counts[0]
+	chi2 := 0.0
+	for _, c := range counts {
+		if c < min {
+			min = c
+		}
+		if c > max {
+			max = c
+		}
+		d := float64(c) - expected
+		chi2 += d * d / expected
+	}
+	df := float64(len(counts) - 1)
+	worst := float64(max) * 100 / float64(total)
+	fmt.Fprintf(w, "%-10s %6d %8d %8d %10.2f %10.3f %8.2f\n", name, len(counts), min, max, chi2, chi2/df, worst)
+	if verbose {
+		for i, c := range counts {
+			fmt.Fprintf(w, "    shard %3d: %d\n", i, c)
+		}
+	}
+}
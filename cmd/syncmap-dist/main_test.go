@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_RunIntegerKeys(t *testing.T) {
+	var in strings.Builder
+	for i := 0; i < 64; i++ {
+		in.WriteString(strconv.Itoa(i) + "\n")
+	}
+	var out bytes.Buffer
+	if err := run([]string{"-shards", "4"}, strings.NewReader(in.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5:\n%s", len(lines), out.String())
+	}
+	if lines[0] != "64 keys" {
+		t.Errorf("got header %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "hasher" || fields[1] != "shards" {
+		t.Errorf("got column names %q", lines[1])
+	}
+	// BKDR is deterministic, unlike the salted default hasher
+	if want := "bkdr            4       15       17       0.12      0.042    26.56"; lines[2] != want {
+		t.Errorf("got %q, want %q", lines[2], want)
+	}
+	for i, name := range []string{"bkdr", "default", "splitmix"} {
+		if fields := strings.Fields(lines[2+i]); fields[0] != name || fields[1] != "4" {
+			t.Errorf("got line %q for hasher %s", lines[2+i], name)
+		}
+	}
+}
+
+func Test_RunStringKeys(t *testing.T) {
+	var out bytes.Buffer
+	in := "a\nb\nc\nd\n"
+	if err := run([]string{"-strings", "-v", "-shards", "2,4"}, strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if !strings.HasPrefix(got, "4 keys\n") {
+		t.Errorf("got header %q", got)
+	}
+	for _, want := range []string{
+		"bkdr            2        2        2       0.00      0.000    50.00\n",
+		"bkdr            4        1        1       0.00      0.000    25.00\n",
+		"default         4",
+		"    shard   3: 1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "splitmix") {
+		t.Errorf("splitmix reported for string keys:\n%s", got)
+	}
+}
+
+func Test_RunInvalidInput(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"-shards", "3"}, strings.NewReader("1\n"), &out); err == nil {
+		t.Error("shard count 3 accepted")
+	}
+	if err := run(nil, strings.NewReader("abc\n"), &out); err == nil {
+		t.Error("string key accepted without -strings")
+	}
+	if err := run(nil, strings.NewReader(""), &out); err == nil {
+		t.Error("empty input accepted")
+	}
+}
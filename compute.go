@@ -0,0 +1,62 @@
+package syncmap
+
+import "sync"
+
+// computation is a call of a GetOrCompute function, shared by the callers
+// asking for its key while it runs.
+type computation[V any] struct {
+	done  chan struct{} // closed once value and err are set
+	value V
+	err   error
+}
+
+// computing holds the running computations of a map by key.
+type computing[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*computation[V]
+}
+
+// GetOrCompute returns the value of the key if present. Otherwise it calls
+// fn and sets the value it returns, unless the key was set meanwhile, in
+// which case that value is kept and returned. Concurrent callers for a
+// missing key share a single call of fn and all get its result; an error is
+// returned to all of them and nothing is set. If fn panics, the panic goes
+// to the caller running it, and the others get ErrComputePanicked. fn runs
+// without any lock of the map held and may call methods of the map.
+func (m *Map[K, V]) GetOrCompute(key K, fn func() (V, error)) (V, error) {
+	if value, ok := m.Get(key); ok {
+		return value, nil
+	}
+	c := &m.computing
+	c.mu.Lock()
+	if call := c.calls[key]; call != nil {
+		c.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	// A computation may have set the key and finished since the Get above.
+	if value, ok := m.Get(key); ok {
+		c.mu.Unlock()
+		return value, nil
+	}
+	call := &computation[V]{done: make(chan struct{}), err: ErrComputePanicked}
+	if c.calls == nil {
+		c.calls = make(map[K]*computation[V])
+	}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	value, err := fn()
+	// The key may have been set by another writer meanwhile.
+	if err == nil {
+		value, _ = m.GetOrSet(key, value)
+	}
+	call.value, call.err = value, err
+	return value, err
+}
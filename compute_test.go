@@ -0,0 +1,103 @@
+package syncmap
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_GetOrCompute(t *testing.T) {
+	m := New64()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := m.GetOrCompute(1, fn); err != nil || v != 1 {
+				t.Error("every caller should get the computed value", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Error("concurrent callers should share one call", calls.Load())
+	}
+	if v, ok := m.Get(1); !ok || v != 1 {
+		t.Error("the computed value should be set", v, ok)
+	}
+
+	fail := errors.New("unavailable")
+	if _, err := m.GetOrCompute(2, func() (interface{}, error) { return nil, fail }); err != fail || m.Has(2) {
+		t.Error("an error should be returned and nothing set", err)
+	}
+	if v, _ := m.GetOrCompute(1, func() (interface{}, error) { return 2, nil }); v != 1 {
+		t.Error("a present value should be returned without computing", v)
+	}
+}
+
+func Test_GetOrComputeFinished(t *testing.T) {
+	m := New64()
+	// Hold the caller between its Get and its check of the running
+	// computations, while another computation sets the key and finishes.
+	m.computing.mu.Lock()
+	result := make(chan interface{})
+	var calls atomic.Int32
+	go func() {
+		v, _ := m.GetOrCompute(1, func() (interface{}, error) {
+			calls.Add(1)
+			return 2, nil
+		})
+		result <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+	m.Set(1, 1)
+	m.computing.mu.Unlock()
+	if v := <-result; v != 1 || calls.Load() != 0 {
+		t.Error("a caller should not compute a key set by a finished computation", v, calls.Load())
+	}
+}
+
+var errLate = errors.New("late waiter")
+
+func Test_GetOrComputePanic(t *testing.T) {
+	m := New64()
+	started := make(chan struct{})
+	waiter := make(chan error)
+	go func() {
+		<-started
+		// Were it late, the waiter would compute the key itself and fail.
+		_, err := m.GetOrCompute(1, func() (interface{}, error) { return nil, errLate })
+		waiter <- err
+	}()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic should reach the caller running fn")
+			}
+		}()
+		m.GetOrCompute(1, func() (interface{}, error) {
+			close(started)
+			time.Sleep(10 * time.Millisecond)
+			panic("boom")
+		})
+	}()
+	if err := <-waiter; !errors.Is(err, ErrComputePanicked) && !errors.Is(err, errLate) {
+		t.Error("waiters should get ErrComputePanicked", err)
+	}
+	if v, err := m.GetOrCompute(1, func() (interface{}, error) { return 2, nil }); err != nil || v != 2 {
+		t.Error("a key should be computed again after a panic", v, err)
+	}
+}
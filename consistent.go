@@ -0,0 +1,116 @@
+package syncmap
+
+// Consistency is the guarantee an iteration gives about the items it sees
+// when the map is written concurrently.
+type Consistency int
+
+const (
+	// Weak only guarantees that each item is read atomically. It is what
+	// Range, IterItems and Snapshot give. It currently costs the same as
+	// ShardConsistent, but may become cheaper.
+	Weak Consistency = iota
+	// ShardConsistent sees the items of each shard as of a single point in
+	// time, with different shards read at different times.
+	ShardConsistent
+	// Strong sees all items as of a single point in time, by read locking
+	// every shard while they are copied, which blocks all writers meanwhile.
+	Strong
+)
+
+// Copies the items that are not expired with the given consistency
+func (m *Map[K, V]) items(level Consistency) []Entry[K, V] {
+	var items []Entry[K, V]
+	if level == Strong {
+		m.ConsistentRead(func(view ReadView[K, V]) {
+			view.Range(func(key K, value V) bool {
+				items = append(items, Entry[K, V]{key, value})
+				return true
+			})
+		})
+		return items
+	}
+	for _, shard := range m.shards() {
+		items = shard.appendItems(items)
+	}
+	return items
+}
+
+// RangeWith is Range with an explicit consistency level. With Strong, all
+// items are copied before fn is first called.
+func (m *Map[K, V]) RangeWith(level Consistency, fn func(key K, value V) bool) {
+	if level != Strong {
+		m.Range(fn)
+		return
+	}
+	for _, item := range m.items(level) {
+		if !fn(item.Key, item.Value) {
+			return
+		}
+	}
+}
+
+// SnapshotWith is Snapshot with an explicit consistency level
+func (m *Map[K, V]) SnapshotWith(level Consistency) map[K]V {
+	items := m.items(level)
+	snapshot := make(map[K]V, len(items))
+	for _, item := range items {
+		snapshot[item.Key] = item.Value
+	}
+	return snapshot
+}
+
+// ReadView gives read access to a map while all of its shards are read
+// locked by ConsistentRead. It must not be used after fn returns.
+type ReadView[K comparable, V any] struct {
+	m *Map[K, V]
+}
+
+// ConsistentRead calls fn with every shard of the map read locked, acquired
+// in order, so everything read through view belongs to a single point in
+// time. All writers are blocked until fn returns, so fn must be short: this
+// is meant for invariant checks and reconciliation, when consistency matters
+// more than latency. fn must not call methods of the map itself, which could
+// deadlock, and must read through view instead.
+func (m *Map[K, V]) ConsistentRead(fn func(view ReadView[K, V])) {
+	shards := m.shards()
+	for _, shard := range shards {
+		shard.RLock()
+	}
+	defer func() {
+		for _, shard := range shards {
+			shard.RUnlock()
+		}
+	}()
+	fn(ReadView[K, V]{m})
+}
+
+// Retrieves a value
+func (v ReadView[K, V]) Get(key K) (value V, ok bool) {
+	return v.m.locate(key).lookup(key)
+}
+
+// Whether the map has the given key
+func (v ReadView[K, V]) Has(key K) bool {
+	_, ok := v.Get(key)
+	return ok
+}
+
+// Returns the number of items that are not expired
+func (v ReadView[K, V]) Size() int {
+	size := 0
+	for _, shard := range v.m.shards() {
+		size += shard.size()
+	}
+	return size
+}
+
+// Calls fn for each item that is not expired until fn returns false
+func (v ReadView[K, V]) Range(fn func(key K, value V) bool) {
+	for _, shard := range v.m.shards() {
+		for key := range shard.items {
+			if value, ok := shard.lookup(key); ok && !fn(key, value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package syncmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_ConsistentRead(t *testing.T) {
+	m := New64()
+	// Writers move a unit between two keys, so their sum stays constant.
+	m.Set(1, 100)
+	m.Set(2, 0)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			m.Update(1, func(old interface{}, _ bool) (interface{}, bool) { return old.(int) - 1, true })
+			m.Update(2, func(old interface{}, _ bool) (interface{}, bool) { return old.(int) + 1, true })
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		m.ConsistentRead(func(view ReadView[uint64, interface{}]) {
+			a, _ := view.Get(1)
+			b, _ := view.Get(2)
+			// A single writer can be between its two updates, never more.
+			if sum := a.(int) + b.(int); sum != 100 && sum != 99 {
+				t.Error("view should be consistent", sum)
+			}
+			if view.Size() != 2 || !view.Has(2) {
+				t.Error("view should see every key")
+			}
+			n := 0
+			view.Range(func(uint64, interface{}) bool { n++; return true })
+			if n != 2 {
+				t.Error("Range should visit every item", n)
+			}
+		})
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func Test_ConsistentReadExpired(t *testing.T) {
+	m := New64()
+	m.Set(1, 1)
+	m.SetWithTTL(2, 2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	m.ConsistentRead(func(view ReadView[uint64, interface{}]) {
+		if view.Size() != m.Size() || view.Size() != 1 {
+			t.Error("view Size should not count expired entries", view.Size())
+		}
+	})
+}
+
+func Test_Consistency(t *testing.T) {
+	m := New64()
+	m.Set(1, 100)
+	m.Set(2, 0)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			m.Update(1, func(old interface{}, _ bool) (interface{}, bool) { return old.(int) - 1, true })
+			m.Update(2, func(old interface{}, _ bool) (interface{}, bool) { return old.(int) + 1, true })
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		snapshot := m.SnapshotWith(Strong)
+		if sum := snapshot[1].(int) + snapshot[2].(int); sum != 100 && sum != 99 {
+			t.Error("strong snapshot should be consistent", sum)
+		}
+		n := 0
+		m.RangeWith(Strong, func(uint64, interface{}) bool { n++; return true })
+		if n != 2 {
+			t.Error("RangeWith should visit every item", n)
+		}
+		if len(m.SnapshotWith(ShardConsistent)) != 2 {
+			t.Error("SnapshotWith should copy every item")
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,114 @@
+package syncmap
+
+import "context"
+
+// The Ctx variants of the basic operations return ctx's error instead of
+// performing the operation when ctx is done, and stop waiting as soon as ctx
+// is done when the operation would block, such as on injected latency.
+
+// Retrieves a value, honoring ctx
+func (m *Map[K, V]) GetCtx(ctx context.Context, key K) (value V, ok bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return value, false, err
+	}
+	if f := m.loadFaults(); f != nil {
+		miss, err := m.injectFaults(ctx, f, key)
+		if err != nil || miss {
+			return value, false, err
+		}
+	}
+	value, ok = m.get(key)
+	return value, ok, nil
+}
+
+// Sets value with the given key, honoring ctx
+func (m *Map[K, V]) SetCtx(ctx context.Context, key K, value V) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f := m.loadFaults(); f != nil {
+		if err := m.delay(ctx, f); err != nil {
+			return err
+		}
+	}
+	m.set(key, value)
+	return nil
+}
+
+// Removes an item, honoring ctx
+func (m *Map[K, V]) DeleteCtx(ctx context.Context, key K) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f := m.loadFaults(); f != nil {
+		if err := m.delay(ctx, f); err != nil {
+			return err
+		}
+	}
+	m.delete(key)
+	return nil
+}
+
+// IterKeysCtx is IterKeys, except that the channel is closed as soon as ctx
+// is done, so a consumer that stops reading early leaks nothing once it
+// cancels ctx.
+func (m *Map[K, V]) IterKeysCtx(ctx context.Context) <-chan K {
+	ch := make(chan K)
+	if !m.inflight.begin() {
+		close(ch)
+		return ch
+	}
+	go func() {
+		defer m.inflight.end()
+		defer close(ch)
+		var items []Entry[K, V]
+		for _, shard := range m.shards() {
+			items = shard.appendItems(items[:0])
+			for _, item := range items {
+				select {
+				case ch <- item.Key:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// IterItemsCtx is IterItems, except that the channel is closed as soon as
+// ctx is done, see IterKeysCtx
+func (m *Map[K, V]) IterItemsCtx(ctx context.Context) <-chan Entry[K, V] {
+	return m.iterItems(ctx, make(chan Entry[K, V]), nil)
+}
+
+// IterWhereCtx is IterWhere, except that the channel is closed as soon as
+// ctx is done, see IterKeysCtx
+func (m *Map[K, V]) IterWhereCtx(ctx context.Context, pred func(key K, value V) bool) <-chan Entry[K, V] {
+	return m.iterItems(ctx, make(chan Entry[K, V]), pred)
+}
+
+// Sends the items matching pred, or all items if pred is nil, to ch from a
+// new goroutine and closes it, or stops as soon as ctx is done
+func (m *Map[K, V]) iterItems(ctx context.Context, ch chan Entry[K, V], pred func(K, V) bool) <-chan Entry[K, V] {
+	if !m.inflight.begin() {
+		close(ch)
+		return ch
+	}
+	go func() {
+		defer m.inflight.end()
+		defer close(ch)
+		var items []Entry[K, V]
+		for _, shard := range m.shards() {
+			items = shard.appendItemsWhere(items[:0], pred)
+			for _, item := range items {
+				select {
+				case ch <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
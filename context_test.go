@@ -0,0 +1,81 @@
+package syncmap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_Ctx64(t *testing.T) {
+	m := New64()
+	ctx := context.Background()
+
+	if err := m.SetCtx(ctx, 1, 1); err != nil {
+		t.Error("SetCtx should succeed", err)
+	}
+	if v, ok, err := m.GetCtx(ctx, 1); err != nil || !ok || v.(int) != 1 {
+		t.Error("GetCtx should return the value", v, ok, err)
+	}
+	if err := m.DeleteCtx(ctx, 1); err != nil || m.Has(1) {
+		t.Error("DeleteCtx should remove the key", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := m.SetCtx(cancelled, 2, 2); !errors.Is(err, context.Canceled) || m.Has(2) {
+		t.Error("SetCtx should not write with a cancelled context", err)
+	}
+	if _, _, err := m.GetCtx(cancelled, 2); !errors.Is(err, context.Canceled) {
+		t.Error("GetCtx should fail with a cancelled context", err)
+	}
+	if err := m.DeleteCtx(cancelled, 2); !errors.Is(err, context.Canceled) {
+		t.Error("DeleteCtx should fail with a cancelled context", err)
+	}
+}
+
+func Test_CtxDeadline64(t *testing.T) {
+	m := New64()
+	m.InjectFaults(&Faults{Latency: time.Hour, LatencyProbability: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.SetCtx(ctx, 1, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("SetCtx should stop waiting at the deadline", err)
+	}
+	m.InjectFaults(nil)
+	if m.Has(1) {
+		t.Error("SetCtx should not write after the deadline")
+	}
+}
+
+func Test_IterCtx64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+
+	n := 0
+	for range m.IterKeysCtx(context.Background()) {
+		n++
+	}
+	if n != 100 {
+		t.Error("IterKeysCtx should return every key", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	items := m.IterItemsCtx(ctx)
+	<-items
+	cancel()
+	for range items {
+	}
+	// Every shard can be written again once the iterator stopped.
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Quiesce(ctx); err != nil {
+		t.Error("the iterator should be done once cancelled", err)
+	}
+}
@@ -0,0 +1,121 @@
+package syncmap
+
+import (
+	"sort"
+	"sync"
+)
+
+// KeyRef is a key of some map, locked by Atomically. Create it with Ref.
+type KeyRef struct {
+	id uint64
+	mu sync.Locker // the shard, whose Unlock publishes lock-free reads
+}
+
+// Returns a reference to the key, to lock it with Atomically
+func (m *Map[K, V]) Ref(key K) KeyRef {
+	shard := m.locate(key)
+	return KeyRef{shard.id, shard}
+}
+
+// Tx gives access to the keys locked by Atomically, through TxGet, TxSet
+// and TxDelete. It must not be used after fn returns.
+type Tx struct {
+	locked map[sync.Locker]bool
+	undo   []func()
+	seen   map[interface{}]bool
+}
+
+// Atomically locks the shards of every given key, in a global order so that
+// concurrent calls cannot deadlock, and calls fn, which reads and writes
+// those keys through TxGet, TxSet and TxDelete. This keeps entries of
+// several maps, such as a forward map and its index map, consistent with
+// each other: no other operation sees them half updated.
+//
+// If fn returns an error or panics, its writes are rolled back before the
+// locks are released, and the error or panic is passed on. Evictions caused
+// by writes to bounded maps are not rolled back. fn must not call methods of
+// the maps, which could deadlock.
+func Atomically(refs []KeyRef, fn func(tx *Tx) error) (err error) {
+	sorted := append([]KeyRef(nil), refs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].id < sorted[j].id })
+	tx := &Tx{locked: make(map[sync.Locker]bool), seen: make(map[interface{}]bool)}
+	for _, ref := range sorted {
+		if !tx.locked[ref.mu] {
+			ref.mu.Lock()
+			tx.locked[ref.mu] = true
+		}
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			for i := len(tx.undo) - 1; i >= 0; i-- {
+				tx.undo[i]()
+			}
+		}
+		for mu := range tx.locked {
+			mu.Unlock()
+		}
+	}()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// txKey identifies a key of a map in the keys written by a Tx.
+type txKey[K comparable, V any] struct {
+	m   *Map[K, V]
+	key K
+}
+
+// Returns the shard of the key, which must be locked by tx
+func txShard[K comparable, V any](tx *Tx, m *Map[K, V], key K) *shard[K, V] {
+	shard := m.locate(key)
+	if !tx.locked[shard] {
+		panic("syncmap: key is not locked by the transaction")
+	}
+	return shard
+}
+
+// Saves the state of the key before its first write in tx, so it can be
+// rolled back
+func txSave[K comparable, V any](tx *Tx, m *Map[K, V], shard *shard[K, V], key K) {
+	id := txKey[K, V]{m, key}
+	if tx.seen[id] {
+		return
+	}
+	tx.seen[id] = true
+	value, exists := shard.lookup(key)
+	e, hasExpiry := shard.expires[key]
+	tx.undo = append(tx.undo, func() {
+		if !exists {
+			shard.remove(key)
+			return
+		}
+		shard.store(key, value)
+		if hasExpiry {
+			shard.setExpiry(key, e)
+		}
+	})
+}
+
+// Retrieves a value of a key locked by tx
+func TxGet[K comparable, V any](tx *Tx, m *Map[K, V], key K) (value V, ok bool) {
+	return txShard(tx, m, key).lookup(key)
+}
+
+// Sets value with the given key locked by tx
+func TxSet[K comparable, V any](tx *Tx, m *Map[K, V], key K, value V) {
+	shard := txShard(tx, m, key)
+	txSave(tx, m, shard, key)
+	shard.store(key, value)
+}
+
+// Removes an item of a key locked by tx
+func TxDelete[K comparable, V any](tx *Tx, m *Map[K, V], key K) {
+	shard := txShard(tx, m, key)
+	txSave(tx, m, shard, key)
+	shard.remove(key)
+}
@@ -0,0 +1,71 @@
+package syncmap
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// Renames a user, keeping the forward map and the index by name consistent
+func rename(users *SyncMap64, byName *SyncMapString, id uint64, name string, fail error) error {
+	old, _ := users.Get(id)
+	refs := []KeyRef{users.Ref(id), byName.Ref(name)}
+	if old != nil {
+		refs = append(refs, byName.Ref(old.(string)))
+	}
+	return Atomically(refs, func(tx *Tx) error {
+		if current, ok := TxGet(tx, users, id); ok {
+			if current != old {
+				return errors.New("renamed concurrently")
+			}
+			TxDelete(tx, byName, current.(string))
+		}
+		TxSet(tx, users, id, interface{}(name))
+		TxSet(tx, byName, name, interface{}(id))
+		return fail
+	})
+}
+
+func Test_Atomically(t *testing.T) {
+	users, byName := New64(), NewString()
+	if err := rename(users, byName, 1, "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+	fail := errors.New("fail")
+	if err := rename(users, byName, 1, "bob", fail); err != fail {
+		t.Error("Atomically should return the error of fn", err)
+	}
+	if v, _ := users.Get(1); v != "alice" || !byName.Has("alice") || byName.Has("bob") {
+		t.Error("writes should be rolled back on error", v)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				rename(users, byName, 1, []string{"a", "b", "c"}[(i+j)%3], nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+	name, _ := users.Get(1)
+	if byName.Size() != 1 || !byName.Has(name.(string)) {
+		t.Error("index should not have dangling entries", byName.Size())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("writing a key that is not locked should panic")
+		}
+		if byName.Has("x") {
+			t.Error("writes should be rolled back on panic")
+		}
+	}()
+	Atomically([]KeyRef{byName.Ref("x")}, func(tx *Tx) error {
+		TxSet(tx, byName, "x", interface{}(2))
+		TxSet(tx, users, 2, interface{}("x"))
+		return nil
+	})
+}
@@ -0,0 +1,125 @@
+package syncmap
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Create a new Map bounding the total cost of its items to about maxCost,
+// with default shard count, for values whose size varies too much for a
+// count of items to bound memory. Each shard keeps its share of maxCost and
+// evicts the items chosen by its policy, created by newPolicy, until a write
+// fits. The cost of items written without SetWithCost is given by sizer, or
+// 1 if sizer is nil. newPolicy may be nil to evict the least recently used
+// items.
+func NewMapWithMaxCost[K comparable, V any](maxCost int64, sizer Sizer[V], newPolicy func() EvictionPolicy[K]) *Map[K, V] {
+	if newPolicy == nil {
+		newPolicy = NewLRU[K]
+	}
+	m := NewMap[K, V]()
+	perShard := maxCost / int64(m.shardCount())
+	if perShard < 1 {
+		perShard = 1
+	}
+	b := &budget{maxCost: perShard * int64(m.shardCount())}
+	newPolicy = m.seededPolicies(newPolicy)
+	for _, shard := range m.shards() {
+		shard.policy = newPolicy()
+		shard.capacity = math.MaxInt
+		shard.costs = make(map[K]int64)
+		shard.maxCost = perShard
+		shard.sizer = sizer
+		shard.budget = b
+	}
+	return m
+}
+
+// SetWithCost sets value with the given key like Set, accounting it as cost
+// in a map created by NewMapWithMaxCost and evicting other items until it
+// fits. The map is left unchanged and the returned error wraps ErrMapFull if
+// cost is larger than the share of a shard. In other maps the cost is
+// ignored.
+func (m *Map[K, V]) SetWithCost(key K, value V, cost int64) error {
+	if f := m.loadFaults(); f != nil {
+		m.delay(context.Background(), f)
+	}
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if shard.costs != nil && cost > shard.maxCost {
+		return fmt.Errorf("%w: cost %d is above the shard budget %d", ErrMapFull, cost, shard.maxCost)
+	}
+	if dst := m.forwardTo(shard); dst != nil {
+		shard.drop(key)
+		return dst.SetWithCost(key, value, cost)
+	}
+	shard.storeWithCost(key, value, cost)
+	return nil
+}
+
+// Returns the cost of a value written without an explicit cost
+func (shard *shard[K, V]) costOf(value V) int64 {
+	if shard.costs == nil {
+		return 0
+	}
+	if shard.sizer != nil {
+		return shard.sizer(value)
+	}
+	return 1
+}
+
+// Evicts items other than key until writing it with the given cost fits,
+// must be called with the write lock held and key removed from the policy
+func (shard *shard[K, V]) makeRoom(key K, exists bool, cost int64) {
+	// Account for the evictions at once, without checking thresholds, so
+	// the usage does not dip below its final value and re-arm them.
+	if b := shard.budget; b != nil {
+		shard.budget = nil
+		entries, total := len(shard.items), shard.cost
+		defer func() {
+			shard.budget = b
+			b.entries.Add(int64(len(shard.items) - entries))
+			b.cost.Add(shard.cost - total)
+		}()
+	}
+	for {
+		others := len(shard.items)
+		if exists {
+			others--
+		}
+		if others == 0 {
+			return
+		}
+		full := !exists && len(shard.items) >= shard.capacity
+		tooCostly := shard.costs != nil && shard.cost-shard.costs[key]+cost > shard.maxCost
+		if !full && !tooCostly {
+			return
+		}
+		shard.remove(shard.policy.Victim())
+		shard.stats.evictions.Add(1)
+	}
+}
+
+// Sets the cost of an item, must be called with the write lock held
+func (shard *shard[K, V]) charge(key K, cost int64) {
+	if shard.costs == nil {
+		return
+	}
+	delta := cost - shard.costs[key]
+	shard.costs[key] = cost
+	shard.cost += delta
+	shard.budget.addCost(delta)
+}
+
+// Forgets the cost of an item, must be called with the write lock held
+func (shard *shard[K, V]) uncharge(key K) {
+	if shard.costs == nil {
+		return
+	}
+	if cost, ok := shard.costs[key]; ok {
+		delete(shard.costs, key)
+		shard.cost -= cost
+		shard.budget.addCost(-cost)
+	}
+}
@@ -0,0 +1,47 @@
+package syncmap
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_SetWithCost(t *testing.T) {
+	sizer := func(v interface{}) int64 { return int64(len(v.(string))) }
+	m := NewMapWithMaxCost[uint64, interface{}](32*100, sizer, nil)
+	keys := firstShardKeys(m, 4)
+
+	m.Set(keys[0], "0123456789")
+	if err := m.SetWithCost(keys[1], "big", 60); err != nil {
+		t.Error("SetWithCost should accept a cost within the shard budget")
+	}
+	if err := m.SetWithCost(keys[2], "huge", 101); !errors.Is(err, ErrMapFull) || m.Has(keys[2]) {
+		t.Error("SetWithCost should reject a cost above the shard budget", err)
+	}
+	if u := m.Usage(); u.Cost != 70 || u.MaxCost != 3200 {
+		t.Error("usage should count the costs", u)
+	}
+
+	m.Get(keys[0])
+	m.SetWithCost(keys[2], "more", 35)
+	if !m.Has(keys[0]) || m.Has(keys[1]) || !m.Has(keys[2]) {
+		t.Error("items should be evicted until the new one fits")
+	}
+	if shard := m.shards()[0]; shard.cost != 45 {
+		t.Error("shard cost should follow evictions", shard.cost)
+	}
+
+	m.SetWithCost(keys[2], "more", 100)
+	if m.Has(keys[0]) || !m.Has(keys[2]) {
+		t.Error("growing an item should evict the others, never the item itself")
+	}
+	m.Delete(keys[2])
+	if u := m.Usage(); u.Cost != 0 || u.Entries != 0 {
+		t.Error("Delete should release the cost", u)
+	}
+
+	m.Set(keys[3], "abc")
+	m.Flush()
+	if u := m.Usage(); u.Cost != 0 {
+		t.Error("Flush should release the cost", u)
+	}
+}
@@ -0,0 +1,49 @@
+package syncmap
+
+// Equal reports whether the map and other hold the same keys, ignoring
+// expired entries, with values equal by eq. A nil eq compares values with
+// ==, and panics if they are not comparable, like CompareAndSwap. The map is
+// copied one shard at a time under its read lock and its entries looked up
+// in other under one read lock per shard of other. The locks of both maps
+// are never held together, so Equal does not compare the maps at a single
+// point in time while they are written.
+func (m *Map[K, V]) Equal(other *Map[K, V], eq func(a, b V) bool) bool {
+	if m == other {
+		return true
+	}
+	if eq == nil {
+		eq = func(a, b V) bool { return any(a) == any(b) }
+	}
+	size := 0
+	var entries []Entry[K, V]
+	for _, shard := range m.shards() {
+		entries = shard.appendItems(entries[:0])
+		size += len(entries)
+		shards, groups := other.groupEntries(entries)
+		for i, group := range groups {
+			if len(group) > 0 && !other.holds(shards[i], group, eq) {
+				return false
+			}
+		}
+	}
+	return size == other.Size()
+}
+
+// Whether the map holds every entry of group, all located in shard, with
+// values equal by eq
+func (m *Map[K, V]) holds(shard *shard[K, V], group []Entry[K, V], eq func(a, b V) bool) bool {
+	shard.RLock()
+	defer shard.RUnlock()
+	for _, e := range group {
+		value, ok := shard.lookup(e.Key)
+		if !ok {
+			if dst := m.forwardTo(shard); dst != nil {
+				value, ok = dst.Get(e.Key)
+			}
+		}
+		if !ok || !eq(e.Value, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Equal64(t *testing.T) {
+	m := NewWithShard64(4)
+	other := NewWithShard64(16)
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+		other.Set(uint64(i), i)
+	}
+	if !m.Equal(other, nil) || !other.Equal(m, nil) || !m.Equal(m, nil) {
+		t.Error("maps with the same entries should be equal")
+	}
+
+	other.Set(100, 100)
+	if m.Equal(other, nil) || other.Equal(m, nil) {
+		t.Error("maps with different keys should not be equal")
+	}
+	other.Delete(100)
+	other.Set(5, -5)
+	if m.Equal(other, nil) {
+		t.Error("maps with different values should not be equal")
+	}
+	abs := func(a, b interface{}) bool {
+		x, y := a.(int), b.(int)
+		return x == y || x == -y
+	}
+	if !m.Equal(other, abs) {
+		t.Error("values should be compared with eq")
+	}
+
+	other.Set(5, 5)
+	other.SetWithTTL(200, 200, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if !m.Equal(other, nil) {
+		t.Error("expired entries should be ignored")
+	}
+}
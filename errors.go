@@ -0,0 +1,38 @@
+package syncmap
+
+import "errors"
+
+// Sentinel errors returned by the error-returning APIs of this package.
+// Callers should compare against them with errors.Is, since they may be
+// wrapped with additional context.
+var (
+	// ErrNotFound is returned by Lookup when the key is not in the map.
+	ErrNotFound = errors.New("syncmap: key not found")
+
+	// ErrMapClosed is returned when starting background work, such as a
+	// Warmup, on a map that is quiescing, see Quiesce.
+	ErrMapClosed = errors.New("syncmap: map is closed")
+
+	// ErrMapFull is returned by SetWithCost when the cost of an item is
+	// larger than the budget of a shard.
+	ErrMapFull = errors.New("syncmap: map is full")
+
+	// ErrVersionMismatch is returned by CompareVersionAndSwap when the entry
+	// has changed since its version was read.
+	ErrVersionMismatch = errors.New("syncmap: version mismatch")
+
+	// ErrValidation is returned when a value or argument fails validation.
+	ErrValidation = errors.New("syncmap: validation failed")
+
+	// ErrSequenceGap is returned when applying a change event whose sequence
+	// number does not follow the last applied one.
+	ErrSequenceGap = errors.New("syncmap: sequence gap")
+
+	// ErrNotReshardable is returned by Reshard for maps whose features it
+	// cannot carry over to new shards.
+	ErrNotReshardable = errors.New("syncmap: map cannot be resharded")
+
+	// ErrComputePanicked is returned to the callers of GetOrCompute waiting
+	// on a computation that panicked.
+	ErrComputePanicked = errors.New("syncmap: compute panicked")
+)
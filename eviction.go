@@ -0,0 +1,238 @@
+package syncmap
+
+import (
+	"container/heap"
+	"container/list"
+	"math/rand/v2"
+)
+
+// EvictionPolicy chooses which item a shard of a capacity-bounded map evicts
+// when a write exceeds its capacity. Every shard has its own policy, and its
+// methods are called with the shard write lock held, so they must be cheap.
+type EvictionPolicy[K comparable] interface {
+	// Touch records a use of the key, either a write or a Get. The key is
+	// new to the policy if it was not touched since its last Remove.
+	Touch(key K)
+	// Remove forgets the key, which was removed from the shard.
+	Remove(key K)
+	// Victim returns the key to evict among those touched and not removed.
+	// It is only called when there is at least one.
+	Victim() K
+	// Reset forgets every key.
+	Reset()
+}
+
+// Create a new Map holding at most about capacity items, with default shard
+// count. Each shard keeps its share of the capacity and evicts the item
+// chosen by its policy, created by newPolicy, when a write exceeds it. Get
+// counts as a use and takes the shard write lock.
+func NewMapWithPolicy[K comparable, V any](capacity int, newPolicy func() EvictionPolicy[K]) *Map[K, V] {
+	m := NewMap[K, V]()
+	m.bound(capacity, newPolicy)
+	return m
+}
+
+// Splits capacity between the shards of a new map, each evicting with a
+// policy created by newPolicy
+func (m *Map[K, V]) bound(capacity int, newPolicy func() EvictionPolicy[K]) {
+	perShard := (capacity + int(m.shardCount()) - 1) / int(m.shardCount())
+	if perShard < 1 {
+		perShard = 1
+	}
+	b := &budget{maxEntries: int64(perShard) * int64(m.shardCount())}
+	newPolicy = m.seededPolicies(newPolicy)
+	for _, shard := range m.shards() {
+		shard.policy = newPolicy()
+		shard.capacity = perShard
+		shard.budget = b
+	}
+}
+
+// Create a new Map holding at most about capacity items, evicting the least
+// recently used ones. See NewMapWithPolicy.
+func NewMapWithCapacity[K comparable, V any](capacity int) *Map[K, V] {
+	return NewMapWithPolicy[K, V](capacity, NewLRU[K])
+}
+
+// lru evicts the least recently used key.
+type lru[K comparable] struct {
+	order    *list.List // of K, most recently used first
+	elements map[K]*list.Element
+}
+
+// Returns a policy evicting the least recently used key
+func NewLRU[K comparable]() EvictionPolicy[K] {
+	l := new(lru[K])
+	l.Reset()
+	return l
+}
+
+func (l *lru[K]) Reset() {
+	l.order = list.New()
+	l.elements = make(map[K]*list.Element)
+}
+
+func (l *lru[K]) Touch(key K) {
+	if e, ok := l.elements[key]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.elements[key] = l.order.PushFront(key)
+}
+
+func (l *lru[K]) Remove(key K) {
+	if e, ok := l.elements[key]; ok {
+		l.order.Remove(e)
+		delete(l.elements, key)
+	}
+}
+
+func (l *lru[K]) Victim() K {
+	return l.order.Back().Value.(K)
+}
+
+// lfuEntry is a key with its use count, ordered in an lfu heap.
+type lfuEntry[K comparable] struct {
+	key   K
+	uses  uint64
+	tick  uint64 // last use, breaking ties between equal counts
+	index int
+}
+
+// lfu evicts the least frequently used key, the least recently used one
+// among keys used as often.
+type lfu[K comparable] struct {
+	entries lfuHeap[K]
+	byKey   map[K]*lfuEntry[K]
+	tick    uint64
+}
+
+// Returns a policy evicting the least frequently used key
+func NewLFU[K comparable]() EvictionPolicy[K] {
+	l := new(lfu[K])
+	l.Reset()
+	return l
+}
+
+func (l *lfu[K]) Reset() {
+	l.entries = nil
+	l.byKey = make(map[K]*lfuEntry[K])
+}
+
+func (l *lfu[K]) Touch(key K) {
+	l.tick++
+	if e, ok := l.byKey[key]; ok {
+		e.uses++
+		e.tick = l.tick
+		heap.Fix(&l.entries, e.index)
+		return
+	}
+	e := &lfuEntry[K]{key: key, uses: 1, tick: l.tick}
+	l.byKey[key] = e
+	heap.Push(&l.entries, e)
+}
+
+func (l *lfu[K]) Remove(key K) {
+	if e, ok := l.byKey[key]; ok {
+		heap.Remove(&l.entries, e.index)
+		delete(l.byKey, key)
+	}
+}
+
+func (l *lfu[K]) Victim() K {
+	return l.entries[0].key
+}
+
+// lfuHeap is a min-heap of entries by use count, then last use.
+type lfuHeap[K comparable] []*lfuEntry[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].uses != h[j].uses {
+		return h[i].uses < h[j].uses
+	}
+	return h[i].tick < h[j].tick
+}
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *lfuHeap[K]) Push(x interface{}) {
+	e := x.(*lfuEntry[K])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *lfuHeap[K]) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// randPolicy is implemented by eviction policies drawing random numbers, so
+// a map can make them draw from its source, see WithRand.
+type randPolicy interface {
+	useRand(intN func(n int) int)
+}
+
+// Returns newPolicy, making the policies it creates draw random numbers
+// from the source of the map
+func (m *Map[K, V]) seededPolicies(newPolicy func() EvictionPolicy[K]) func() EvictionPolicy[K] {
+	return func() EvictionPolicy[K] {
+		p := newPolicy()
+		if r, ok := p.(randPolicy); ok {
+			r.useRand(m.randIntN)
+		}
+		return p
+	}
+}
+
+// randomEviction evicts a key chosen uniformly at random.
+type randomEviction[K comparable] struct {
+	keys    []K
+	indexes map[K]int
+	intN    func(n int) int // nil for the global source
+}
+
+// Returns a policy evicting a random key, which costs no bookkeeping on Get
+func NewRandomEviction[K comparable]() EvictionPolicy[K] {
+	r := new(randomEviction[K])
+	r.Reset()
+	return r
+}
+
+func (r *randomEviction[K]) Reset() {
+	r.keys = nil
+	r.indexes = make(map[K]int)
+}
+
+func (r *randomEviction[K]) Touch(key K) {
+	if _, ok := r.indexes[key]; !ok {
+		r.indexes[key] = len(r.keys)
+		r.keys = append(r.keys, key)
+	}
+}
+
+func (r *randomEviction[K]) Remove(key K) {
+	i, ok := r.indexes[key]
+	if !ok {
+		return
+	}
+	last := len(r.keys) - 1
+	r.keys[i] = r.keys[last]
+	r.indexes[r.keys[i]] = i
+	r.keys = r.keys[:last]
+	delete(r.indexes, key)
+}
+
+func (r *randomEviction[K]) Victim() K {
+	if r.intN == nil {
+		return r.keys[rand.IntN(len(r.keys))]
+	}
+	return r.keys[r.intN(len(r.keys))]
+}
+
+func (r *randomEviction[K]) useRand(intN func(n int) int) {
+	r.intN = intN
+}
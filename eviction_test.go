@@ -0,0 +1,83 @@
+package syncmap
+
+import "testing"
+
+func Test_NewWithCapacity(t *testing.T) {
+	m := NewWithCapacity64(64)
+	shard := m.shards()[0]
+	if shard.capacity != 2 {
+		t.Fatal("capacity should be split among shards", shard.capacity)
+	}
+
+	// Find three keys of the first shard.
+	var keys []uint64
+	for key := uint64(0); len(keys) < 3; key++ {
+		if m.shardIndex(key) == 0 {
+			keys = append(keys, key)
+		}
+	}
+	m.Set(keys[0], 0)
+	m.Set(keys[1], 1)
+	m.Get(keys[0])
+	m.Set(keys[2], 2)
+	if !m.Has(keys[0]) || m.Has(keys[1]) || !m.Has(keys[2]) {
+		t.Error("the least recently used item should be evicted")
+	}
+	if len(shard.items) != 2 {
+		t.Error("shard should hold its capacity", len(shard.items))
+	}
+
+	for i := 0; i < 1000; i++ {
+		m.Set(uint64(i), i)
+	}
+	if m.Size() > 64 {
+		t.Error("map should not grow beyond its capacity", m.Size())
+	}
+	m.Flush()
+	if len(shard.policy.(*lru[uint64]).elements) != 0 {
+		t.Error("Flush should reset the LRU order")
+	}
+}
+
+// Returns n keys of the first shard of m
+func firstShardKeys(m *SyncMap64, n int) []uint64 {
+	var keys []uint64
+	for key := uint64(0); len(keys) < n; key++ {
+		if m.shardIndex(key) == 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func Test_EvictionPolicy(t *testing.T) {
+	m := NewMapWithPolicy[uint64, interface{}](64, NewLFU[uint64])
+	keys := firstShardKeys(m, 3)
+	m.Set(keys[0], 0)
+	m.Set(keys[1], 1)
+	m.Get(keys[0])
+	m.Get(keys[1])
+	m.Get(keys[1])
+	m.Set(keys[2], 2)
+	if m.Has(keys[0]) || !m.Has(keys[1]) {
+		t.Error("LFU should evict the least frequently used item")
+	}
+	m.Set(keys[0], 0)
+	if m.Has(keys[2]) {
+		t.Error("LFU should evict the least recently used item among equals")
+	}
+
+	m = NewMapWithPolicy[uint64, interface{}](64, NewRandomEviction[uint64])
+	for i := 0; i < 1000; i++ {
+		m.Set(uint64(i), i)
+	}
+	if m.Size() != 64 {
+		t.Error("random eviction should keep the map at capacity", m.Size())
+	}
+	for i := 0; i < 1000; i++ {
+		m.Delete(uint64(i))
+	}
+	if p := m.shards()[0].policy.(*randomEviction[uint64]); len(p.keys) != 0 || len(p.indexes) != 0 {
+		t.Error("removed keys should be forgotten by the policy")
+	}
+}
@@ -0,0 +1,140 @@
+package syncmap
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Encoder encodes the items of a map one at a time, see Export.
+type Encoder[K comparable, V any] interface {
+	Encode(key K, value V) error
+}
+
+// EncoderFunc adapts a function to an Encoder.
+type EncoderFunc[K comparable, V any] func(key K, value V) error
+
+// Calls f
+func (f EncoderFunc[K, V]) Encode(key K, value V) error {
+	return f(key, value)
+}
+
+// Decoder decodes items one at a time, returning io.EOF at the end of the
+// input, see Import.
+type Decoder[K comparable, V any] interface {
+	Decode() (key K, value V, err error)
+}
+
+// Codec is a serialization format for the items of a map, to choose the
+// format of Export and Import. The package provides JSONCodec, GobCodec and
+// MsgpackCodec.
+type Codec[K comparable, V any] interface {
+	NewEncoder(w io.Writer) Encoder[K, V]
+	NewDecoder(r io.Reader) Decoder[K, V]
+}
+
+// Export passes every item of the map to enc, shard by shard, and returns
+// the first error of enc. Only one shard is copied at a time, under its read
+// lock, and enc is called without any lock held, so exporting a very large
+// map needs memory for its largest shard rather than for a full snapshot.
+// The export is shard consistent, see Consistency.
+func (m *Map[K, V]) Export(enc Encoder[K, V]) error {
+	var items []Entry[K, V]
+	for _, shard := range m.shards() {
+		items = shard.appendItems(items[:0])
+		for _, item := range items {
+			if err := enc.Encode(item.Key, item.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Import sets every item decoded by dec in the map until dec returns io.EOF,
+// and returns the number of items set
+func (m *Map[K, V]) Import(dec Decoder[K, V]) (n int, err error) {
+	for {
+		key, value, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		m.Set(key, value)
+		n++
+	}
+}
+
+// JSONCodec encodes each item as a JSON object with key and value fields,
+// followed by a newline.
+type JSONCodec[K comparable, V any] struct{}
+
+// See NewJSONEncoder
+func (JSONCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	return NewJSONEncoder[K, V](w)
+}
+
+// Returns a Decoder reading the items written by a JSON Encoder
+func (JSONCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return jsonDecoder[K, V]{json.NewDecoder(r)}
+}
+
+type jsonDecoder[K comparable, V any] struct {
+	dec *json.Decoder
+}
+
+func (d jsonDecoder[K, V]) Decode() (key K, value V, err error) {
+	var e jsonEntry[K, V]
+	err = d.dec.Decode(&e)
+	return e.Key, e.Value, err
+}
+
+// GobCodec encodes items as Entry values of a gob stream.
+type GobCodec[K comparable, V any] struct{}
+
+// See NewGobEncoder
+func (GobCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	return NewGobEncoder[K, V](w)
+}
+
+// Returns a Decoder reading the items written by a gob Encoder
+func (GobCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return gobDecoder[K, V]{gob.NewDecoder(r)}
+}
+
+type gobDecoder[K comparable, V any] struct {
+	dec *gob.Decoder
+}
+
+func (d gobDecoder[K, V]) Decode() (key K, value V, err error) {
+	var e Entry[K, V]
+	err = d.dec.Decode(&e)
+	return e.Key, e.Value, err
+}
+
+// Returns an Encoder writing each item to w as a JSON object with key and
+// value fields, followed by a newline
+func NewJSONEncoder[K comparable, V any](w io.Writer) Encoder[K, V] {
+	enc := json.NewEncoder(w)
+	return EncoderFunc[K, V](func(key K, value V) error {
+		return enc.Encode(jsonEntry[K, V]{key, value})
+	})
+}
+
+// An item encoded by the JSON Encoder
+type jsonEntry[K comparable, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+// Returns an Encoder writing each item to w as an Entry in a gob stream,
+// which can be read back by decoding Entry values until io.EOF
+func NewGobEncoder[K comparable, V any](w io.Writer) Encoder[K, V] {
+	enc := gob.NewEncoder(w)
+	return EncoderFunc[K, V](func(key K, value V) error {
+		return enc.Encode(Entry[K, V]{key, value})
+	})
+}
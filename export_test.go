@@ -0,0 +1,55 @@
+package syncmap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_Export(t *testing.T) {
+	m := New64()
+	for i := uint64(0); i < 100; i++ {
+		m.Set(i, int(i))
+	}
+
+	var buf bytes.Buffer
+	if err := m.Export(NewGobEncoder[uint64, interface{}](&buf)); err != nil {
+		t.Fatal(err)
+	}
+	dec := gob.NewDecoder(&buf)
+	n := 0
+	for {
+		var item Item64
+		if err := dec.Decode(&item); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if item.Value != int(item.Key) {
+			t.Error("exported item should hold its value", item)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Error("export should encode every item", n)
+	}
+
+	buf.Reset()
+	m.Flush()
+	m.Set(7, "seven")
+	if err := m.Export(NewJSONEncoder[uint64, interface{}](&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != `{"key":7,"value":"seven"}` {
+		t.Error("JSON encoder should write an object per item", got)
+	}
+
+	errStop := errors.New("stop")
+	err := m.Export(EncoderFunc[uint64, interface{}](func(uint64, interface{}) error { return errStop }))
+	if err != errStop {
+		t.Error("export should return the encoder's error", err)
+	}
+}
@@ -0,0 +1,23 @@
+package syncmap
+
+import "expvar"
+
+// PublishExpvar publishes the size and Stats of the map as an expvar
+// variable with the given name, so they show up under /debug/vars. The
+// values are read each time the variable is. Like expvar.Publish, it panics
+// if the name is already in use.
+func (m *Map[K, V]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		s := m.Stats()
+		return map[string]any{
+			"size":        m.Size(),
+			"hits":        s.Hits,
+			"misses":      s.Misses,
+			"hit_ratio":   s.HitRatio(),
+			"sets":        s.Sets,
+			"deletes":     s.Deletes,
+			"evictions":   s.Evictions,
+			"expirations": s.Expirations,
+		}
+	}))
+}
@@ -0,0 +1,65 @@
+package syncmap
+
+import (
+	"context"
+	"time"
+)
+
+// Faults configures artificial failures injected into a Map, so that
+// applications can test how they behave when the cache degrades. It is meant
+// for tests only. Probabilities are in the range [0, 1].
+type Faults struct {
+	// Latency is added to Get, Set and Delete with LatencyProbability.
+	Latency            time.Duration
+	LatencyProbability float64
+
+	// MissProbability is the probability that Get reports a miss for a key
+	// that exists. The entry is kept.
+	MissProbability float64
+
+	// EvictProbability is the probability that Get evicts the key it reads
+	// and reports a miss.
+	EvictProbability float64
+}
+
+// InjectFaults starts injecting the given faults into the map operations.
+// Passing nil stops injecting faults.
+func (m *Map[K, V]) InjectFaults(f *Faults) {
+	m.faults.Store(f)
+}
+
+func (m *Map[K, V]) loadFaults() *Faults {
+	f, _ := m.faults.Load().(*Faults)
+	return f
+}
+
+// Sleeps for the injected latency, returns early with ctx's error when ctx
+// is done first
+func (m *Map[K, V]) delay(ctx context.Context, f *Faults) error {
+	if f.Latency <= 0 || m.randFloat64() >= f.LatencyProbability {
+		return nil
+	}
+	timer := time.NewTimer(f.Latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Applies the faults to a Get of key, returns whether Get must report a miss
+func (m *Map[K, V]) injectFaults(ctx context.Context, f *Faults, key K) (bool, error) {
+	if err := m.delay(ctx, f); err != nil {
+		return false, err
+	}
+	if f.EvictProbability > 0 && m.randFloat64() < f.EvictProbability {
+		shard := m.locate(key)
+		shard.Lock()
+		shard.remove(key)
+		shard.Unlock()
+		return true, nil
+	}
+	return f.MissProbability > 0 && m.randFloat64() < f.MissProbability, nil
+}
@@ -0,0 +1,40 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_InjectFaults64(t *testing.T) {
+	m := New64()
+	m.Set(1, 1)
+	m.Set(2, 2)
+
+	m.InjectFaults(&Faults{MissProbability: 1})
+	if _, ok := m.Get(1); ok {
+		t.Error("Get should report a spurious miss")
+	}
+	if m.Size() != 2 {
+		t.Error("spurious misses should keep the entry")
+	}
+
+	m.InjectFaults(&Faults{EvictProbability: 1})
+	if _, ok := m.Get(1); ok {
+		t.Error("Get should report a miss on forced eviction")
+	}
+	if m.Size() != 1 {
+		t.Error("forced eviction should remove the entry")
+	}
+
+	m.InjectFaults(&Faults{Latency: 10 * time.Millisecond, LatencyProbability: 1})
+	start := time.Now()
+	m.Set(3, 3)
+	if time.Since(start) < 10*time.Millisecond {
+		t.Error("Set should be delayed by the injected latency")
+	}
+
+	m.InjectFaults(nil)
+	if _, ok := m.Get(2); !ok {
+		t.Error("Get should succeed once faults are disabled")
+	}
+}
@@ -0,0 +1,44 @@
+package syncmap
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Fence returns an epoch token for a write barrier. Once WaitForEpoch returns
+// for that token, every write that was in progress or completed when Fence
+// was called is visible to all readers.
+func (m *Map[K, V]) Fence() uint64 {
+	return atomic.AddUint64(&m.epoch, 1)
+}
+
+// WaitForEpoch blocks until all writes started before the fence that returned
+// epoch are visible, or ctx is done. It passes through the write lock of
+// every shard once, so it never blocks writers for longer than one of their
+// own operations.
+func (m *Map[K, V]) WaitForEpoch(ctx context.Context, epoch uint64) error {
+	if atomic.LoadUint64(&m.visible) >= epoch {
+		return nil
+	}
+	// Any barrier started from now on covers the requested epoch as well.
+	target := atomic.LoadUint64(&m.epoch)
+	for _, s := range m.shards() {
+		passed := make(chan struct{})
+		go func(s *shard[K, V]) {
+			s.Lock()
+			s.Unlock()
+			close(passed)
+		}(s)
+		select {
+		case <-passed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for {
+		visible := atomic.LoadUint64(&m.visible)
+		if visible >= target || atomic.CompareAndSwapUint64(&m.visible, visible, target) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package syncmap
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_Fence64(t *testing.T) {
+	m := New64()
+	m.Set(1, 1)
+
+	e1 := m.Fence()
+	e2 := m.Fence()
+	if e2 <= e1 {
+		t.Error("Fence should return increasing epochs")
+	}
+
+	if err := m.WaitForEpoch(context.Background(), e2); err != nil {
+		t.Error("WaitForEpoch should succeed", err)
+	}
+	if err := m.WaitForEpoch(context.Background(), e1); err != nil {
+		t.Error("WaitForEpoch should succeed for an older epoch", err)
+	}
+}
+
+func Test_WaitForEpochBlocked64(t *testing.T) {
+	m := NewWithShard64(2)
+	m.Set(1, 1)
+
+	// Simulate a write in progress on a shard.
+	shard := m.locate(1)
+	shard.Lock()
+	epoch := m.Fence()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.WaitForEpoch(ctx, epoch); err == nil {
+		t.Error("WaitForEpoch should wait for writes in progress")
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- m.WaitForEpoch(context.Background(), epoch)
+	}()
+	shard.Unlock()
+	if err := <-done; err != nil {
+		t.Error("WaitForEpoch should succeed once writes complete", err)
+	}
+}
@@ -0,0 +1,67 @@
+package syncmap
+
+import "sync/atomic"
+
+// Fork returns a new map with the same entries and their expirations,
+// sharing storage with the map copy-on-write: a shard is copied the first
+// time either map writes to it, so a what-if computation can mutate its fork
+// freely while the map keeps serving, and untouched shards are never copied.
+// Each shard is forked under its lock, so concurrent writes may or may not be
+// part of the fork. The fork has the same shard count but is otherwise a
+// plain map: soft-deleted items, indexes, eviction policies, budgets and
+// hooks are not carried over.
+func (m *Map[K, V]) Fork() *Map[K, V] {
+	shards := m.shards()
+	fork := NewMapWithShard[K, V](uint32(len(shards)))
+	fork.hash = m.hash
+	for i, shard := range shards {
+		shard.Lock()
+		if shard.cow == nil {
+			shard.cow = new(atomic.Int32)
+			shard.cow.Store(1)
+		}
+		shard.cow.Add(1)
+		forked := fork.shards()[i]
+		forked.items = shard.items
+		forked.expires = shard.expires
+		forked.cow = shard.cow
+		shard.Unlock()
+	}
+	return fork
+}
+
+// Copies items and expires if they are shared with forks, must be called
+// with the write lock held before writing to them
+func (shard *shard[K, V]) own() {
+	shard.dirty = true
+	if shard.cow == nil {
+		return
+	}
+	// Copy before giving up the share: once the count drops to one, the
+	// last sharer writes in place.
+	if shard.cow.Load() > 1 {
+		items := make(map[K]V, len(shard.items))
+		for key, value := range shard.items {
+			items[key] = value
+		}
+		shard.items = items
+		if shard.expires != nil {
+			expires := make(map[K]expiry, len(shard.expires))
+			for key, e := range shard.expires {
+				expires[key] = e
+			}
+			shard.expires = expires
+		}
+	}
+	shard.release()
+}
+
+// Gives up sharing items and expires without copying them, must be called
+// with the write lock held before replacing them
+func (shard *shard[K, V]) release() {
+	shard.dirty = true
+	if shard.cow != nil {
+		shard.cow.Add(-1)
+		shard.cow = nil
+	}
+}
@@ -0,0 +1,64 @@
+package syncmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Fork(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+	m.ExpireMany([]uint64{1}, time.Hour)
+
+	fork := m.Fork()
+	if fork.Size() != 100 {
+		t.Error("fork should have every entry", fork.Size())
+	}
+	if _, ok := fork.TTL(1); !ok {
+		t.Error("fork should keep expirations")
+	}
+
+	fork.Set(1, "fork")
+	fork.Delete(2)
+	m.Set(3, "original")
+	if v, _ := m.Get(1); v.(int) != 1 || !m.Has(2) {
+		t.Error("writes to the fork should not be seen by the map", v)
+	}
+	if v, _ := fork.Get(3); v.(int) != 3 {
+		t.Error("writes to the map should not be seen by the fork", v)
+	}
+
+	shared := 0
+	for i, shard := range m.shards() {
+		if shard.cow != nil && fork.shards()[i].cow == shard.cow {
+			shared++
+		}
+	}
+	if shared < int(m.shardCount())-3 {
+		t.Error("untouched shards should stay shared", shared)
+	}
+
+	// Concurrent writers on both sides and a fork of the fork.
+	forkOfFork := fork.Fork()
+	var wg sync.WaitGroup
+	for _, target := range []*SyncMap64{m, fork, forkOfFork} {
+		wg.Add(1)
+		go func(target *SyncMap64) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				target.Set(uint64(i), target)
+			}
+		}(target)
+	}
+	wg.Wait()
+	for _, target := range []*SyncMap64{m, fork, forkOfFork} {
+		for i := 0; i < 100; i++ {
+			if v, _ := target.Get(uint64(i)); v != target {
+				t.Fatal("each map should only see its own writes", i)
+			}
+		}
+	}
+}
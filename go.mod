@@ -0,0 +1,3 @@
+module github.com/DeanThompson/syncmap
+
+go 1.24
@@ -0,0 +1,42 @@
+package syncmap
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// The gob encoding of a map
+type gobMap[K comparable, V any] struct {
+	ShardCount uint32
+	Items      map[K]V
+}
+
+// GobEncode encodes the shard count and the items of the map, read as
+// Snapshot does. Expiration times are not encoded. As with any gob encoded
+// interface value, concrete types of interface{} values other than the basic
+// types must be registered with gob.Register.
+func (m *Map[K, V]) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(gobMap[K, V]{m.shardCount(), m.Snapshot()})
+	return buf.Bytes(), err
+}
+
+// GobDecode sets the encoded items in the map, keeping the items already
+// present. A zero Map, such as one allocated by encoding/gob, is given the
+// encoded shard count; an existing map keeps its own.
+func (m *Map[K, V]) GobDecode(data []byte) error {
+	var decoded gobMap[K, V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+		return err
+	}
+	if m.table.Load() == nil {
+		if !isPowerOfTwo(decoded.ShardCount) {
+			decoded.ShardCount = defaultShardCount
+		}
+		m.init(decoded.ShardCount)
+	}
+	for key, value := range decoded.Items {
+		m.Set(key, value)
+	}
+	return nil
+}
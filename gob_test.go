@@ -0,0 +1,36 @@
+package syncmap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+func Test_Gob(t *testing.T) {
+	m := NewWithShard64(8)
+	for i := uint64(0); i < 100; i++ {
+		m.Set(i, int(i))
+	}
+	m.Set(100, "hundred")
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	var restored *SyncMap64
+	if err := gob.NewDecoder(&buf).Decode(&restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.shardCount() != 8 {
+		t.Error("decoded map should keep the shard count", restored.shardCount())
+	}
+	if restored.Size() != 101 {
+		t.Error("decoded map should hold every item", restored.Size())
+	}
+	if v, ok := restored.Get(42); !ok || v != 42 {
+		t.Error("decoded map should hold the values", v, ok)
+	}
+	if v, _ := restored.Get(100); v != "hundred" {
+		t.Error("decoded map should hold values of any basic type", v)
+	}
+}
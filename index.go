@@ -0,0 +1,100 @@
+package syncmap
+
+// Extractor returns the attribute of a value indexed by a secondary index,
+// and false if the value must not be indexed. Attributes must be comparable.
+type Extractor[V any] func(value V) (attr interface{}, ok bool)
+
+// index is a secondary index of one shard, mapping attributes to keys.
+type index[K comparable, V any] struct {
+	extract Extractor[V]
+	keys    map[interface{}]map[K]struct{}
+	attrs   map[K]interface{}
+}
+
+func newIndex[K comparable, V any](extract Extractor[V]) *index[K, V] {
+	ix := &index[K, V]{extract: extract}
+	ix.reset()
+	return ix
+}
+
+func (ix *index[K, V]) reset() {
+	ix.keys = make(map[interface{}]map[K]struct{})
+	ix.attrs = make(map[K]interface{})
+}
+
+func (ix *index[K, V]) add(key K, value V) {
+	ix.remove(key)
+	attr, ok := ix.extract(value)
+	if !ok {
+		return
+	}
+	keys := ix.keys[attr]
+	if keys == nil {
+		keys = make(map[K]struct{})
+		ix.keys[attr] = keys
+	}
+	keys[key] = struct{}{}
+	ix.attrs[key] = attr
+}
+
+func (ix *index[K, V]) remove(key K) {
+	attr, ok := ix.attrs[key]
+	if !ok {
+		return
+	}
+	delete(ix.attrs, key)
+	keys := ix.keys[attr]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(ix.keys, attr)
+	}
+}
+
+// IndexBy registers a secondary index named name, built from the attribute
+// extract returns for each value. The index is built from the current items
+// and then maintained under the shard locks by every write, so it is always
+// consistent with the map. Registering an existing name replaces the index.
+func (m *Map[K, V]) IndexBy(name string, extract Extractor[V]) {
+	for _, shard := range m.shards() {
+		shard.Lock()
+		ix := newIndex[K, V](extract)
+		for key, value := range shard.items {
+			ix.add(key, value)
+		}
+		if shard.indexes == nil {
+			shard.indexes = make(map[string]*index[K, V])
+		}
+		shard.indexes[name] = ix
+		shard.Unlock()
+	}
+}
+
+// Removes the secondary index named name
+func (m *Map[K, V]) DropIndex(name string) {
+	for _, shard := range m.shards() {
+		shard.Lock()
+		delete(shard.indexes, name)
+		if len(shard.indexes) == 0 {
+			shard.indexes = nil
+		}
+		shard.Unlock()
+	}
+}
+
+// Returns the keys whose value has the attribute attr in the index named
+// name, or nil if there is no such index
+func (m *Map[K, V]) KeysByIndex(name string, attr interface{}) []K {
+	var keys []K
+	for _, shard := range m.shards() {
+		shard.RLock()
+		if ix, ok := shard.indexes[name]; ok {
+			for key := range ix.keys[attr] {
+				if _, ok := shard.lookup(key); ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+		shard.RUnlock()
+	}
+	return keys
+}
@@ -0,0 +1,61 @@
+package syncmap
+
+import (
+	"sort"
+	"testing"
+)
+
+type job struct {
+	status string
+}
+
+func byStatus(v interface{}) (interface{}, bool) {
+	j, ok := v.(job)
+	return j.status, ok
+}
+
+func sortedKeys(keys []uint64) []uint64 {
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func Test_IndexBy(t *testing.T) {
+	m := New64()
+	m.Set(1, job{"pending"})
+	m.Set(2, job{"done"})
+	m.IndexBy("status", byStatus)
+
+	m.Set(3, job{"pending"})
+	m.Set(4, "not a job")
+	if keys := sortedKeys(m.KeysByIndex("status", "pending")); len(keys) != 2 || keys[0] != 1 || keys[1] != 3 {
+		t.Error("KeysByIndex should return existing and new keys", keys)
+	}
+
+	m.Set(1, job{"done"})
+	m.Delete(3)
+	if keys := m.KeysByIndex("status", "pending"); len(keys) != 0 {
+		t.Error("index should follow updates and deletes", keys)
+	}
+	if keys := m.KeysByIndex("status", "done"); len(keys) != 2 {
+		t.Error("index should have updated keys", keys)
+	}
+
+	m.CompareAndSwap(2, job{"done"}, job{"pending"})
+	if keys := m.KeysByIndex("status", "pending"); len(keys) != 1 || keys[0] != 2 {
+		t.Error("index should follow CompareAndSwap", keys)
+	}
+
+	m.Flush()
+	if keys := m.KeysByIndex("status", "done"); len(keys) != 0 {
+		t.Error("Flush should empty the index", keys)
+	}
+	m.Set(5, job{"done"})
+	if keys := m.KeysByIndex("status", "done"); len(keys) != 1 {
+		t.Error("index should be maintained after Flush", keys)
+	}
+
+	m.DropIndex("status")
+	if keys := m.KeysByIndex("status", "done"); keys != nil {
+		t.Error("KeysByIndex should return nil for a dropped index", keys)
+	}
+}
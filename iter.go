@@ -0,0 +1,27 @@
+package syncmap
+
+import "iter"
+
+// All returns an iterator over the items of the map, for use with
+// for key, value := range m.All(). It has the guarantees of Range: each
+// shard is copied under its read lock and the loop body runs without any
+// lock held, and stopping early leaks nothing.
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.Range(yield)
+	}
+}
+
+// Returns an iterator over the keys of the map, see All
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.Range(func(key K, _ V) bool { return yield(key) })
+	}
+}
+
+// Returns an iterator over the values of the map, see All
+func (m *Map[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.Range(func(_ K, value V) bool { return yield(value) })
+	}
+}
@@ -0,0 +1,81 @@
+package syncmap
+
+import "testing"
+
+func Test_All(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+
+	n := 0
+	for key, value := range m.All() {
+		if value.(int) != int(key) {
+			t.Error("All should yield the value of each key", key)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Error("All should yield every item", n)
+	}
+
+	sum := uint64(0)
+	for key := range m.Keys() {
+		sum += key
+	}
+	if sum != 4950 {
+		t.Error("Keys should yield every key", sum)
+	}
+
+	n = 0
+	for value := range m.Values() {
+		if value.(int) < 0 {
+			t.Error("wrong value", value)
+		}
+		if n++; n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Error("Values should stop on break", n)
+	}
+}
+
+func Test_Sorted(t *testing.T) {
+	m := New64()
+	for _, key := range []uint64{42, 7, 1000, 3, 99} {
+		m.Set(key, key)
+	}
+	keys := m.KeysSorted()
+	want := []uint64{3, 7, 42, 99, 1000}
+	if len(keys) != len(want) {
+		t.Fatal("KeysSorted should return every key", keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Error("KeysSorted should return ascending keys", keys)
+			break
+		}
+	}
+
+	var seen []uint64
+	for key, value := range m.AllSorted() {
+		if value.(uint64) != key {
+			t.Error("AllSorted should yield the key's value", key, value)
+		}
+		seen = append(seen, key)
+		if len(seen) == 3 {
+			break
+		}
+	}
+	if len(seen) != 3 || seen[0] != 3 || seen[2] != 42 {
+		t.Error("AllSorted should yield ascending keys and stop early", seen)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("KeysSorted should panic for unordered keys")
+		}
+	}()
+	NewMap[struct{ a int }, int]().KeysSorted()
+}
@@ -0,0 +1,29 @@
+package syncmap
+
+import "encoding/json"
+
+// MarshalJSON encodes the map as a JSON object, as encoding/json encodes a
+// map[K]V: integer keys are written as strings, and keys of other types must
+// be strings or implement encoding.TextMarshaler. The items are read as
+// Snapshot does.
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Snapshot())
+}
+
+// UnmarshalJSON sets the items of a JSON object in the map, keeping the
+// items already present, as encoding/json does for a map[K]V. A zero Map,
+// such as one allocated by encoding/json for a nil pointer, is given the
+// default shard count.
+func (m *Map[K, V]) UnmarshalJSON(data []byte) error {
+	var items map[K]V
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if m.table.Load() == nil {
+		m.init(defaultShardCount)
+	}
+	for key, value := range items {
+		m.Set(key, value)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package syncmap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_JSON(t *testing.T) {
+	m := New64()
+	m.Set(1, "one")
+	m.Set(2, 2.5)
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"1":"one","2":2.5}` {
+		t.Error("map should encode as an object with string keys", string(data))
+	}
+
+	var config struct {
+		Items  *SyncMap64
+		Labels SyncMapString
+	}
+	if err := json.Unmarshal([]byte(`{"Items":{"7":"seven"},"Labels":{"a":1}}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := config.Items.Get(7); !ok || v != "seven" {
+		t.Error("nil map pointer should be decoded", v, ok)
+	}
+	if v, ok := config.Labels.Get("a"); !ok || v != 1.0 {
+		t.Error("zero map should be decoded", v, ok)
+	}
+
+	if err := json.Unmarshal([]byte(`{"x":1}`), m); err == nil {
+		t.Error("non-integer key should fail to decode into a SyncMap64")
+	}
+	if m.Size() != 2 {
+		t.Error("failed decoding should not change the map", m.Size())
+	}
+}
@@ -0,0 +1,115 @@
+package syncmap
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeyCipher encrypts keys with AES-GCM, so snapshots written by SaveTo and
+// items written by Export do not leak raw user identifiers, yet can be read
+// back by a holder of the secret. Unlike a KeyMasker, the same key encrypts
+// differently every time. Keys are encoded with gob before encryption.
+type KeyCipher[K comparable] struct {
+	aead cipher.AEAD
+}
+
+// NewKeyCipher returns a KeyCipher using secret as the AES key. The error
+// wraps ErrValidation unless secret is 16, 24 or 32 bytes long.
+func NewKeyCipher[K comparable](secret []byte) (*KeyCipher[K], error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyCipher[K]{aead}, nil
+}
+
+// Returns the encryption of key, encoded with unpadded URL-safe base64
+func (c *KeyCipher[K]) Encrypt(key K) (string, error) {
+	var plain bytes.Buffer
+	if err := gob.NewEncoder(&plain).Encode(&key); err != nil {
+		return "", err
+	}
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+plain.Len()+c.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, plain.Bytes(), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Returns the key encrypted by Encrypt. Fails for a string that was not
+// encrypted with the same secret.
+func (c *KeyCipher[K]) Decrypt(s string) (key K, err error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return key, fmt.Errorf("syncmap: decrypting key: %w", err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return key, errors.New("syncmap: decrypting key: too short")
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return key, fmt.Errorf("syncmap: decrypting key: %w", err)
+	}
+	err = gob.NewDecoder(bytes.NewReader(plain)).Decode(&key)
+	return key, err
+}
+
+// SetKeyCipher makes SaveTo encrypt the keys it writes with c, and LoadFrom
+// decrypt them. Passing nil saves raw keys again.
+func (m *Map[K, V]) SetKeyCipher(c *KeyCipher[K]) {
+	m.keyCipher.Store(c)
+}
+
+// EncryptedKeys returns a Codec encrypting keys with c, then writing them as
+// strings in the format of codec, for Export and Import, for example
+// EncryptedKeys(c, JSONCodec[string, V]{}).
+func EncryptedKeys[K comparable, V any](c *KeyCipher[K], codec Codec[string, V]) Codec[K, V] {
+	return encryptedCodec[K, V]{c, codec}
+}
+
+type encryptedCodec[K comparable, V any] struct {
+	cipher *KeyCipher[K]
+	codec  Codec[string, V]
+}
+
+func (e encryptedCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	enc := e.codec.NewEncoder(w)
+	return EncoderFunc[K, V](func(key K, value V) error {
+		s, err := e.cipher.Encrypt(key)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(s, value)
+	})
+}
+
+func (e encryptedCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return encryptedDecoder[K, V]{e.cipher, e.codec.NewDecoder(r)}
+}
+
+type encryptedDecoder[K comparable, V any] struct {
+	cipher *KeyCipher[K]
+	dec    Decoder[string, V]
+}
+
+func (d encryptedDecoder[K, V]) Decode() (key K, value V, err error) {
+	s, value, err := d.dec.Decode()
+	if err != nil {
+		return key, value, err
+	}
+	key, err = d.cipher.Decrypt(s)
+	return key, value, err
+}
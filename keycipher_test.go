@@ -0,0 +1,85 @@
+package syncmap
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_KeyCipherSaveLoad(t *testing.T) {
+	if _, err := NewKeyCipher[string]([]byte("short")); !errors.Is(err, ErrValidation) {
+		t.Error("a secret of the wrong length should be rejected", err)
+	}
+	secret := []byte("0123456789abcdef")
+	c, err := NewKeyCipher[string](secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMap[string, int]()
+	m.Set("alice@example.com", 1)
+	m.SetWithTTL("bob@example.com", 2, time.Hour)
+	m.SetKeyCipher(c)
+	var buf bytes.Buffer
+	if err := m.SaveTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("example.com")) {
+		t.Error("the snapshot should not hold raw keys")
+	}
+
+	if err := NewMap[string, int]().LoadFrom(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrValidation) {
+		t.Error("loading encrypted keys without a cipher should fail", err)
+	}
+	other, _ := NewKeyCipher[string]([]byte("fedcba9876543210"))
+	wrong := NewMap[string, int]()
+	wrong.SetKeyCipher(other)
+	if err := wrong.LoadFrom(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("loading with another secret should fail")
+	}
+
+	restored := NewMap[string, int]()
+	restored.SetKeyCipher(c)
+	if err := restored.LoadFrom(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Equal(m, nil) {
+		t.Error("the snapshot should round trip", restored.Size())
+	}
+	if ttl, ok := restored.TTL("bob@example.com"); !ok || ttl < 59*time.Minute {
+		t.Error("the snapshot should keep expirations", ttl, ok)
+	}
+
+	// A map with a cipher still loads snapshots with raw keys.
+	m.SetKeyCipher(nil)
+	buf.Reset()
+	m.SaveTo(&buf)
+	if err := restored.LoadFrom(&buf); err != nil {
+		t.Error("raw snapshots should load with a cipher", err)
+	}
+}
+
+func Test_EncryptedKeysExport(t *testing.T) {
+	c, _ := NewKeyCipher[string]([]byte("0123456789abcdef"))
+	codec := EncryptedKeys(c, JSONCodec[string, int]{})
+
+	m := NewMap[string, int]()
+	m.Set("alice@example.com", 1)
+	m.Set("bob@example.com", 2)
+	var buf bytes.Buffer
+	if err := m.Export(codec.NewEncoder(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("example.com")) {
+		t.Error("the export should not hold raw keys")
+	}
+
+	restored := NewMap[string, int]()
+	if n, err := restored.Import(codec.NewDecoder(&buf)); err != nil || n != 2 {
+		t.Fatal(n, err)
+	}
+	if !restored.Equal(m, nil) {
+		t.Error("the export should round trip", restored.Size())
+	}
+}
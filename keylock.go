@@ -0,0 +1,65 @@
+package syncmap
+
+import "sync"
+
+// Number of stripes of the table of key locks
+const keyLockStripes = 64
+
+// keyLock is the mutex of a key, kept in its stripe while held or waited on.
+type keyLock struct {
+	sync.Mutex
+	refs int // callers holding or waiting for the lock
+}
+
+// keyLockStripe holds the locks of the keys hashing to it.
+type keyLockStripe[K comparable] struct {
+	mu    sync.Mutex
+	locks map[K]*keyLock
+}
+
+// keyLocks is the table of key locks of a map, separate from its shards so
+// holding a key lock blocks no operation of the map, and so Reshard does
+// not affect it.
+type keyLocks[K comparable] [keyLockStripes]keyLockStripe[K]
+
+// LockKey locks the key, waiting until no other caller holds it, and returns
+// a function unlocking it, which panics if called again. Key locks are
+// advisory: they do not lock the entry of the key, and only exclude other
+// callers of LockKey, so an expensive operation keyed by the same key, such
+// as loading it from a database, can be guarded without blocking the shard
+// of the key. Locks of distinct keys never block each other.
+func (m *Map[K, V]) LockKey(key K) (unlock func()) {
+	table := m.keyLocks.Load()
+	if table == nil {
+		m.keyLocks.CompareAndSwap(nil, new(keyLocks[K]))
+		table = m.keyLocks.Load()
+	}
+	stripe := &table[m.hash(key)%keyLockStripes]
+
+	stripe.mu.Lock()
+	l := stripe.locks[key]
+	if l == nil {
+		if stripe.locks == nil {
+			stripe.locks = make(map[K]*keyLock)
+		}
+		l = new(keyLock)
+		stripe.locks[key] = l
+	}
+	l.refs++
+	stripe.mu.Unlock()
+
+	l.Lock()
+	unlocked := false
+	return func() {
+		if unlocked {
+			panic("syncmap: key unlocked twice")
+		}
+		unlocked = true
+		l.Unlock()
+		stripe.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(stripe.locks, key)
+		}
+		stripe.mu.Unlock()
+	}
+}
@@ -0,0 +1,55 @@
+package syncmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_LockKey(t *testing.T) {
+	m := New64()
+	counter := 0
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				unlock := m.LockKey(1)
+				counter++
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 800 {
+		t.Error("LockKey should exclude other holders of the key", counter)
+	}
+
+	// Neither another key nor the entry of the key are blocked.
+	unlock := m.LockKey(1)
+	done := make(chan struct{})
+	go func() {
+		m.LockKey(2)()
+		m.Set(1, 1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("a key lock should only block LockKey of the same key")
+	}
+	unlock()
+
+	for i := range m.keyLocks.Load() {
+		if n := len(m.keyLocks.Load()[i].locks); n != 0 {
+			t.Error("released key locks should be forgotten", n)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("unlocking twice should panic")
+		}
+	}()
+	unlock()
+}
@@ -0,0 +1,66 @@
+package syncmap
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// KeyMasker turns a key into the string written in metric labels by
+// WriteOpenMetrics, and in debug output built with ExportKey, so they do not
+// leak raw user identifiers. The map itself always operates on the raw keys.
+// Masking is one-way, so formats that are read back use a KeyCipher instead,
+// see SetKeyCipher for SaveTo and EncryptedKeys for Export. JSON and gob
+// encoding of the map write the raw keys.
+type KeyMasker[K comparable] func(key K) string
+
+// HMACKeyMasker returns a KeyMasker writing the hex encoded HMAC-SHA256 of
+// each key, truncated to 128 bits. The same key and secret always give the
+// same string, so masked artifacts can still be joined and diffed.
+func HMACKeyMasker[K comparable](secret []byte) KeyMasker[K] {
+	return func(key K) string {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(keyBytes(key))
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+}
+
+// Returns the bytes identifying key: integers as 8 big-endian bytes, strings
+// as is, and the default format of fmt for other types
+func keyBytes[K comparable](key K) []byte {
+	var buf [8]byte
+	switch k := any(key).(type) {
+	case uint64:
+		binary.BigEndian.PutUint64(buf[:], k)
+	case uint32:
+		binary.BigEndian.PutUint64(buf[:], uint64(k))
+	case int:
+		binary.BigEndian.PutUint64(buf[:], uint64(k))
+	case int64:
+		binary.BigEndian.PutUint64(buf[:], uint64(k))
+	case int32:
+		binary.BigEndian.PutUint64(buf[:], uint64(k))
+	case string:
+		return []byte(k)
+	default:
+		return []byte(fmt.Sprint(key))
+	}
+	return buf[:]
+}
+
+// SetKeyMasker sets the masker used by ExportKey and WriteOpenMetrics.
+// Passing nil exports keys in the default format of fmt.
+func (m *Map[K, V]) SetKeyMasker(mask KeyMasker[K]) {
+	m.keyMasker.Store(mask)
+}
+
+// Returns the representation of key in metric labels and debug output,
+// masked by the masker of the map if any
+func (m *Map[K, V]) ExportKey(key K) string {
+	if mask, _ := m.keyMasker.Load().(KeyMasker[K]); mask != nil {
+		return mask(key)
+	}
+	return fmt.Sprint(key)
+}
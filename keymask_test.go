@@ -0,0 +1,30 @@
+package syncmap
+
+import "testing"
+
+func Test_ExportKey64(t *testing.T) {
+	m := New64()
+	if m.ExportKey(42) != "42" {
+		t.Error("ExportKey should return the decimal key without masker")
+	}
+
+	m.SetKeyMasker(HMACKeyMasker[uint64]([]byte("secret")))
+	masked := m.ExportKey(42)
+	if masked == "42" || len(masked) != 32 {
+		t.Error("ExportKey should return the masked key", masked)
+	}
+	if m.ExportKey(42) != masked {
+		t.Error("masking should be deterministic")
+	}
+	if m.ExportKey(43) == masked {
+		t.Error("different keys should be masked differently")
+	}
+	if HMACKeyMasker[uint64]([]byte("other"))(42) == masked {
+		t.Error("masking should depend on the secret")
+	}
+
+	m.SetKeyMasker(nil)
+	if m.ExportKey(42) != "42" {
+		t.Error("SetKeyMasker(nil) should disable masking")
+	}
+}
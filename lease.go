@@ -0,0 +1,79 @@
+package syncmap
+
+import "time"
+
+// Lease is the value stored for a key held with AcquireLease. Leases are
+// regular entries with a TTL, so they are released automatically when their
+// owner stops renewing them.
+type Lease struct {
+	Owner string
+}
+
+// AcquireLease takes the lease on key for owner for ttl, if the key is free
+// or the lease is already held by owner, in which case it is renewed.
+// Returns whether owner holds the lease. Panics if V cannot hold a Lease.
+func (m *Map[K, V]) AcquireLease(key K, owner string, ttl time.Duration) bool {
+	lease, ok := any(Lease{owner}).(V)
+	if !ok {
+		panic("syncmap: values cannot hold a Lease")
+	}
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if value, ok := shard.lookup(key); ok {
+		if current, isLease := any(value).(Lease); !isLease || current.Owner != owner {
+			return false
+		}
+	} else if dst := m.forwardTo(shard); dst != nil {
+		return dst.AcquireLease(key, owner, ttl)
+	}
+	shard.store(key, lease)
+	shard.expire(key, ttl)
+	return true
+}
+
+// RenewLease extends the lease on key for ttl from now, returns false if
+// owner does not hold the lease.
+func (m *Map[K, V]) RenewLease(key K, owner string, ttl time.Duration) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if _, ok := shard.lookup(key); !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.RenewLease(key, owner, ttl)
+		}
+	}
+	if !shard.holdsLease(key, owner) {
+		return false
+	}
+	shard.expire(key, ttl)
+	return true
+}
+
+// ReleaseLease frees the lease on key, returns false if owner does not hold
+// the lease.
+func (m *Map[K, V]) ReleaseLease(key K, owner string) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if _, ok := shard.lookup(key); !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.ReleaseLease(key, owner)
+		}
+	}
+	if !shard.holdsLease(key, owner) {
+		return false
+	}
+	shard.remove(key)
+	return true
+}
+
+// Whether owner holds the lease on key, must be called with the lock held
+func (shard *shard[K, V]) holdsLease(key K, owner string) bool {
+	value, ok := shard.lookup(key)
+	if !ok {
+		return false
+	}
+	lease, ok := any(value).(Lease)
+	return ok && lease.Owner == owner
+}
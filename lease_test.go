@@ -0,0 +1,57 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Lease(t *testing.T) {
+	m := NewString()
+	if !m.AcquireLease("partition-1", "worker-a", time.Hour) {
+		t.Error("AcquireLease should take a free lease")
+	}
+	if m.AcquireLease("partition-1", "worker-b", time.Hour) {
+		t.Error("AcquireLease should not take a lease held by another owner")
+	}
+	if !m.AcquireLease("partition-1", "worker-a", time.Hour) {
+		t.Error("AcquireLease should succeed for the current owner")
+	}
+	if v, _ := m.Get("partition-1"); v.(Lease).Owner != "worker-a" {
+		t.Error("the lease should be stored as the value of the key")
+	}
+
+	if m.RenewLease("partition-1", "worker-b", time.Hour) {
+		t.Error("RenewLease should fail for another owner")
+	}
+	if m.ReleaseLease("partition-1", "worker-b") {
+		t.Error("ReleaseLease should fail for another owner")
+	}
+	if !m.ReleaseLease("partition-1", "worker-a") || m.Has("partition-1") {
+		t.Error("ReleaseLease should free the lease")
+	}
+
+	m.Set("config", "value")
+	if m.AcquireLease("config", "worker-a", time.Hour) {
+		t.Error("AcquireLease should not overwrite a regular value")
+	}
+}
+
+func Test_LeaseExpiration(t *testing.T) {
+	m := NewString()
+	m.AcquireLease("device-1", "worker-a", 5*time.Millisecond)
+	if !m.RenewLease("device-1", "worker-a", 20*time.Millisecond) {
+		t.Error("RenewLease should extend a held lease")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if !m.Has("device-1") {
+		t.Error("renewed lease should not expire at its initial TTL")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if m.RenewLease("device-1", "worker-a", time.Hour) {
+		t.Error("RenewLease should fail once the lease expired")
+	}
+	if !m.AcquireLease("device-1", "worker-b", time.Hour) {
+		t.Error("an expired lease should be released automatically")
+	}
+}
@@ -0,0 +1,293 @@
+package syncmap
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// This file holds a small linearizability checker in the spirit of
+// porcupine. Concurrent goroutines record the operations they run on a
+// handful of keys, together with logical call and return timestamps, and the
+// checker searches for a sequential order of every key's history that
+// respects real time and the sequential model below. Operations on different
+// keys are independent, so each key is checked on its own.
+//
+// New compound operations should add a kind to the model and to
+// runLinearizabilityWorkload, so their atomicity is checked along with the
+// basic operations.
+
+const (
+	opGet = iota
+	opSet
+	opDelete
+	opGetOrSet
+	opCompareAndSwap
+	opCompareAndDelete
+	opSwap
+	opGetAndDelete
+	opUpdate
+	opUpsert
+	opSetIfPresent
+)
+
+// Number of operation kinds run by runLinearizabilityWorkload
+const opKinds = 11
+
+// linOp is one operation of a recorded history.
+type linOp struct {
+	kind      int
+	key       uint64
+	input     int
+	expect    int // expected value of a compare operation
+	output    int
+	ok        bool
+	call, ret int64
+}
+
+// linState is the sequential model of a single key.
+type linState struct {
+	value   int
+	present bool
+}
+
+// step applies op to state and reports whether the recorded output is
+// possible from that state.
+func (s linState) step(op linOp) (linState, bool) {
+	switch op.kind {
+	case opGet:
+		if op.ok != s.present {
+			return s, false
+		}
+		return s, !s.present || op.output == s.value
+	case opSet:
+		return linState{op.input, true}, true
+	case opDelete:
+		return linState{}, true
+	case opGetOrSet:
+		if s.present {
+			return s, op.ok && op.output == s.value
+		}
+		return linState{op.input, true}, !op.ok && op.output == op.input
+	case opCompareAndSwap:
+		if s.present && s.value == op.expect {
+			return linState{op.input, true}, op.ok
+		}
+		return s, !op.ok
+	case opCompareAndDelete:
+		if s.present && s.value == op.expect {
+			return linState{}, op.ok
+		}
+		return s, !op.ok
+	case opSwap:
+		if op.ok != s.present || (s.present && op.output != s.value) {
+			return s, false
+		}
+		return linState{op.input, true}, true
+	case opGetAndDelete:
+		if op.ok != s.present || (s.present && op.output != s.value) {
+			return s, false
+		}
+		return linState{}, true
+	case opUpdate:
+		// The update removes even values and replaces the others.
+		if op.ok != s.present || (s.present && op.output != s.value) {
+			return s, false
+		}
+		if s.present && s.value%2 == 0 {
+			return linState{}, true
+		}
+		return linState{op.input, true}, true
+	case opUpsert:
+		// The upsert sums the existing and incoming values.
+		if s.present {
+			return linState{s.value + op.input, true}, op.output == s.value+op.input
+		}
+		return linState{op.input, true}, op.output == op.input
+	case opSetIfPresent:
+		if s.present {
+			return linState{op.input, true}, op.ok
+		}
+		return s, !op.ok
+	}
+	return s, false
+}
+
+// linearizable reports whether a single key's history has a legal
+// sequential order. The history must hold at most 64 operations.
+func linearizable(history []linOp) bool {
+	type memoKey struct {
+		done  uint64
+		state linState
+	}
+	seen := make(map[memoKey]bool)
+	all := uint64(1)<<uint(len(history)) - 1
+
+	var search func(done uint64, state linState) bool
+	search = func(done uint64, state linState) bool {
+		if done == all {
+			return true
+		}
+		k := memoKey{done, state}
+		if seen[k] {
+			return false
+		}
+		seen[k] = true
+
+		// Only operations invoked before the earliest pending return can
+		// be linearized next.
+		minRet := int64(-1)
+		for i, op := range history {
+			if done&(1<<uint(i)) == 0 && (minRet < 0 || op.ret < minRet) {
+				minRet = op.ret
+			}
+		}
+		for i, op := range history {
+			if done&(1<<uint(i)) != 0 || op.call > minRet {
+				continue
+			}
+			if next, ok := state.step(op); ok && search(done|1<<uint(i), next) {
+				return true
+			}
+		}
+		return false
+	}
+	return search(0, linState{})
+}
+
+// recorder collects a concurrent history with logical timestamps.
+type recorder struct {
+	clock int64
+	mu    sync.Mutex
+	ops   []linOp
+}
+
+func (r *recorder) now() int64 {
+	return atomic.AddInt64(&r.clock, 1)
+}
+
+func (r *recorder) add(op linOp) {
+	r.mu.Lock()
+	r.ops = append(r.ops, op)
+	r.mu.Unlock()
+}
+
+func (r *recorder) byKey() map[uint64][]linOp {
+	histories := make(map[uint64][]linOp)
+	for _, op := range r.ops {
+		histories[op.key] = append(histories[op.key], op)
+	}
+	return histories
+}
+
+// runLinearizabilityWorkload runs random operations on m from several
+// goroutines and returns the recorded history.
+func runLinearizabilityWorkload(m *SyncMap64, workers, opsPerWorker, keys int) *recorder {
+	r := new(recorder)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < opsPerWorker; i++ {
+				op := linOp{kind: rnd.Intn(opKinds), key: uint64(rnd.Intn(keys))}
+				op.call = r.now()
+				switch op.kind {
+				case opGet:
+					v, ok := m.Get(op.key)
+					op.ok = ok
+					if ok {
+						op.output = v.(int)
+					}
+				case opSet:
+					op.input = w*opsPerWorker + i + 1
+					m.Set(op.key, op.input)
+				case opDelete:
+					m.Delete(op.key)
+				case opGetOrSet:
+					op.input = w*opsPerWorker + i + 1
+					v, loaded := m.GetOrSet(op.key, op.input)
+					op.ok, op.output = loaded, v.(int)
+				case opCompareAndSwap:
+					// Expect a value written by this worker, or any recent one.
+					op.input = w*opsPerWorker + i + 1
+					op.expect = rnd.Intn(op.input) + 1
+					op.ok = m.CompareAndSwap(op.key, op.expect, op.input)
+				case opCompareAndDelete:
+					op.expect = rnd.Intn(w*opsPerWorker+i+1) + 1
+					op.ok = m.CompareAndDelete(op.key, op.expect)
+				case opSwap:
+					op.input = w*opsPerWorker + i + 1
+					v, loaded := m.Swap(op.key, op.input)
+					op.ok = loaded
+					if loaded {
+						op.output = v.(int)
+					}
+				case opGetAndDelete:
+					v, ok := m.GetAndDelete(op.key)
+					op.ok = ok
+					if ok {
+						op.output = v.(int)
+					}
+				case opUpdate:
+					op.input = w*opsPerWorker + i + 1
+					m.Update(op.key, func(old interface{}, exists bool) (interface{}, bool) {
+						op.ok = exists
+						if !exists {
+							return op.input, true
+						}
+						op.output = old.(int)
+						return op.input, op.output%2 != 0
+					})
+				case opUpsert:
+					op.input = w*opsPerWorker + i + 1
+					v := m.Upsert(op.key, op.input, func(existing, incoming interface{}) interface{} {
+						return existing.(int) + incoming.(int)
+					})
+					op.output = v.(int)
+				case opSetIfPresent:
+					op.input = w*opsPerWorker + i + 1
+					op.ok = m.SetIfPresent(op.key, op.input)
+				}
+				op.ret = r.now()
+				r.add(op)
+			}
+		}(w)
+	}
+	wg.Wait()
+	return r
+}
+
+func Test_LinearizableChecker(t *testing.T) {
+	// set(1) completes before a get that observes nothing: not linearizable.
+	bad := []linOp{
+		{kind: opSet, input: 1, call: 1, ret: 2},
+		{kind: opGet, ok: false, call: 3, ret: 4},
+	}
+	if linearizable(bad) {
+		t.Error("checker should reject a stale read")
+	}
+
+	// The same operations overlapping in time can be ordered get, set.
+	overlapping := []linOp{
+		{kind: opSet, input: 1, call: 1, ret: 4},
+		{kind: opGet, ok: false, call: 2, ret: 3},
+	}
+	if !linearizable(overlapping) {
+		t.Error("checker should accept overlapping operations")
+	}
+}
+
+func Test_Linearizable64(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		m := NewWithShard64(2)
+		r := runLinearizabilityWorkload(m, 4, 12, 3)
+		for key, history := range r.byKey() {
+			if !linearizable(history) {
+				t.Fatalf("history of key %d is not linearizable: %+v", key, history)
+			}
+		}
+	}
+}
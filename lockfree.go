@@ -0,0 +1,71 @@
+package syncmap
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// The items and expirations of a shard as of its last write, read without
+// locking by maps with lock-free reads. They are never written to: the
+// shard copies them before its next write.
+type shardView[K comparable, V any] struct {
+	items   map[K]V
+	expires map[K]expiry
+}
+
+// Whether the key is present and not expired in the view
+func (v *shardView[K, V]) lookup(key K) (value V, ok bool) {
+	value, ok = v.items[key]
+	if ok && v.expires != nil {
+		if e, has := v.expires[key]; has && e.expired(time.Now().UnixNano()) {
+			var zero V
+			return zero, false
+		}
+	}
+	return
+}
+
+// WithLockFreeReads makes Get and Has read without taking any lock, for
+// read-dominated workloads where RLock and RUnlock contend between many
+// readers. Each shard publishes its items behind an atomic pointer and
+// writers copy the shard before writing to it once it was published, so
+// every write costs a copy of its shard: use a high shard count, and only
+// for maps written rarely. Maps with sliding expiration or an eviction
+// policy keep locking, since their Get writes. The mode cannot be turned
+// off. Returns the map for chaining.
+func (m *Map[K, V]) WithLockFreeReads() *Map[K, V] {
+	for _, shard := range m.shards() {
+		shard.Lock()
+		shard.lockFree = true
+		shard.dirty = true
+		shard.Unlock() // publishes the shard
+	}
+	m.lockFree.Store(true)
+	return m
+}
+
+// Unlock releases the write lock, first logging the keys written if the map
+// has a write-ahead log, and publishing the items of the shard if it has
+// lock-free reads and was written to
+func (shard *shard[K, V]) Unlock() {
+	if len(shard.touched) > 0 {
+		shard.logTouched()
+	}
+	if shard.lockFree && shard.dirty {
+		shard.publish()
+	}
+	shard.RWMutex.Unlock()
+}
+
+// Publishes the items of the shard for lock-free reads, sharing them with
+// the view copy-on-write, must be called with the write lock held
+func (shard *shard[K, V]) publish() {
+	shard.dirty = false
+	if shard.cow == nil {
+		shard.cow = new(atomic.Int32)
+		shard.cow.Store(1)
+	}
+	// The view's share is never given back, so the next write copies.
+	shard.cow.Add(1)
+	shard.published.Store(&shardView[K, V]{shard.items, shard.expires})
+}
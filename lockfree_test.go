@@ -0,0 +1,75 @@
+package syncmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_LockFreeReads(t *testing.T) {
+	m := New64()
+	m.Set(1, "one")
+	m.WithLockFreeReads()
+	if v, ok := m.Get(1); !ok || v != "one" {
+		t.Error("entries set before enabling should be readable", v, ok)
+	}
+	m.Set(2, "two")
+	m.Delete(1)
+	if m.Has(1) || !m.Has(2) {
+		t.Error("reads should see the last write")
+	}
+	m.SetWithTTL(3, "three", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if m.Has(3) {
+		t.Error("reads should not see expired entries")
+	}
+	m.Flush()
+	if m.Has(2) {
+		t.Error("reads should not see flushed entries")
+	}
+
+	fork := m.Fork()
+	m.Set(4, "four")
+	fork.Set(5, "five")
+	if fork.Has(4) || m.Has(5) {
+		t.Error("a fork should stay independent of lock-free reads")
+	}
+}
+
+func Test_LockFreeReadsConcurrent(t *testing.T) {
+	m := NewWithShard64(4).WithLockFreeReads()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := uint64(w*1000 + i)
+				m.Set(key, i)
+				if v, ok := m.Get(key); !ok || v != i {
+					t.Error("a write should be visible to the writer's next read", key, v, ok)
+					return
+				}
+				m.Get(uint64(i))
+			}
+		}(w)
+	}
+	wg.Wait()
+	if m.Size() != 2000 {
+		t.Error("all writes should be kept", m.Size())
+	}
+}
+
+func Test_LockFreeReadsAtomically(t *testing.T) {
+	m := New64().WithLockFreeReads()
+	err := Atomically([]KeyRef{m.Ref(1)}, func(tx *Tx) error {
+		TxSet(tx, m, 1, interface{}("one"))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m.Get(1); !ok || v != "one" {
+		t.Error("writes of a transaction should be visible to lock-free reads", v, ok)
+	}
+}
@@ -0,0 +1,64 @@
+package syncmap
+
+import "sync"
+
+// maintenance lets background tasks of a map, such as expiration sweeps or
+// snapshots, be paused during latency critical windows. A task that comes
+// due while paused waits for the resume and then runs immediately, catching
+// up on the work it skipped.
+type maintenance struct {
+	mu     sync.Mutex
+	paused int
+	resume chan struct{} // closed when maintenance resumes
+}
+
+// PauseMaintenance stops background tasks of the map from running until
+// ResumeMaintenance is called. Calls nest: maintenance resumes only after
+// ResumeMaintenance has been called once for each PauseMaintenance.
+func (m *Map[K, V]) PauseMaintenance() {
+	m.maintenance.mu.Lock()
+	if m.maintenance.paused == 0 {
+		m.maintenance.resume = make(chan struct{})
+	}
+	m.maintenance.paused++
+	m.maintenance.mu.Unlock()
+}
+
+// ResumeMaintenance lets background tasks run again. Tasks that came due
+// while paused run right away.
+func (m *Map[K, V]) ResumeMaintenance() {
+	m.maintenance.mu.Lock()
+	defer m.maintenance.mu.Unlock()
+	if m.maintenance.paused == 0 {
+		panic("syncmap: ResumeMaintenance without PauseMaintenance")
+	}
+	m.maintenance.paused--
+	if m.maintenance.paused == 0 {
+		close(m.maintenance.resume)
+		m.maintenance.resume = nil
+	}
+}
+
+// Whether background tasks are paused
+func (m *Map[K, V]) MaintenancePaused() bool {
+	m.maintenance.mu.Lock()
+	defer m.maintenance.mu.Unlock()
+	return m.maintenance.paused > 0
+}
+
+// Returns a channel that is closed once maintenance is allowed to run.
+// Background tasks must wait on it before each run.
+func (mt *maintenance) allowed() <-chan struct{} {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.paused == 0 {
+		return closedChan
+	}
+	return mt.resume
+}
+
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
+}
@@ -0,0 +1,35 @@
+package syncmap
+
+import "testing"
+
+func Test_PauseMaintenance64(t *testing.T) {
+	m := New64()
+	if m.MaintenancePaused() {
+		t.Error("maintenance should not be paused on a new map")
+	}
+	select {
+	case <-m.maintenance.allowed():
+	default:
+		t.Error("maintenance should be allowed on a new map")
+	}
+
+	m.PauseMaintenance()
+	m.PauseMaintenance()
+	allowed := m.maintenance.allowed()
+	m.ResumeMaintenance()
+	if !m.MaintenancePaused() {
+		t.Error("pauses should nest")
+	}
+	select {
+	case <-allowed:
+		t.Error("maintenance should not be allowed while paused")
+	default:
+	}
+
+	m.ResumeMaintenance()
+	select {
+	case <-allowed:
+	default:
+		t.Error("tasks waiting for maintenance should run after resume")
+	}
+}
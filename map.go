@@ -0,0 +1,1166 @@
+package syncmap
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shard wraps built-in map by using RWMutex for concurrent safe.
+type shard[K comparable, V any] struct {
+	items map[K]V
+	// expires holds the expiration of keys that have a TTL. It is nil
+	// until the first TTL is set on the shard.
+	expires map[K]expiry
+	// ttl is the expiration of values stored without one, see WithTTL.
+	ttl time.Duration
+	// deleted holds soft-deleted items, nil until the first SoftDelete.
+	deleted map[K]tombstone[V]
+	// indexes holds the secondary indexes by name, nil without any index.
+	indexes map[string]*index[K, V]
+	// policy chooses the items to evict in capacity-bounded maps, else nil.
+	policy   EvictionPolicy[K]
+	capacity int
+	// costs holds the cost of each item in cost-bounded maps, else nil.
+	costs   map[K]int64
+	cost    int64 // total cost of the items
+	maxCost int64
+	sizer   Sizer[V]
+	// budget tracks the usage of a bounded map, shared by its shards, else nil.
+	budget *budget
+	id     uint64 // orders the locks taken by Atomically
+	// cow counts the shards sharing items and expires since a Fork, else nil.
+	cow   *atomic.Int32
+	stats shardStats
+	// statsOn is the flag of the map telling whether stats are counted.
+	statsOn *atomic.Bool
+	// published holds the items for lock-free reads, see WithLockFreeReads.
+	published atomic.Pointer[shardView[K, V]]
+	lockFree  bool
+	dirty     bool // whether items or expires changed since published
+	// movedTo is the map Reshard moves the items to once the shard is
+	// retired, else nil.
+	movedTo atomic.Pointer[Map[K, V]]
+	// wal is the log of the map, else nil, and touched the keys written
+	// since the shard was locked, logged when it is unlocked.
+	wal     *wal[K, V]
+	touched map[K]struct{}
+	// versions holds the versions given out by GetVersioned for keys not
+	// written since, nil until the first one, and version the last given.
+	versions map[K]uint64
+	version  uint64
+	sync.RWMutex
+}
+
+// Last id given to a shard
+var shardIDs atomic.Uint64
+
+// Whether the key is present and not expired, must be called with the lock held
+func (shard *shard[K, V]) lookup(key K) (value V, ok bool) {
+	value, ok = shard.items[key]
+	if ok && shard.expires != nil {
+		if e, has := shard.expires[key]; has && e.expired(time.Now().UnixNano()) {
+			var zero V
+			return zero, false
+		}
+	}
+	return
+}
+
+// Writes a value keeping its expiration, must be called with the write lock held
+func (shard *shard[K, V]) put(key K, value V) {
+	shard.putWithCost(key, value, shard.costOf(value))
+}
+
+// Writes a value of the given cost keeping its expiration, evicting other
+// items if the shard is bounded, and counts it as a set. Must be called with
+// the write lock held.
+func (shard *shard[K, V]) putWithCost(key K, value V, cost int64) {
+	shard.place(key, value, cost)
+	if shard.statsOn.Load() {
+		shard.stats.sets.Add(1)
+	}
+}
+
+// Writes a value like putWithCost without counting it, for an entry moved
+// between shards. Must be called with the write lock held.
+func (shard *shard[K, V]) place(key K, value V, cost int64) {
+	_, exists := shard.items[key]
+	if shard.policy != nil {
+		// Forget the key first, so it is never its own victim.
+		shard.policy.Remove(key)
+		shard.makeRoom(key, exists, cost)
+	}
+	if shard.budget != nil && !exists {
+		shard.budget.addEntries(1)
+	}
+	shard.own()
+	shard.touch(key)
+	delete(shard.versions, key)
+	shard.items[key] = value
+	for _, ix := range shard.indexes {
+		ix.add(key, value)
+	}
+	if shard.policy != nil {
+		shard.policy.Touch(key)
+	}
+	shard.charge(key, cost)
+}
+
+// Stores a value with the default expiration of the shard, must be called
+// with the write lock held
+func (shard *shard[K, V]) store(key K, value V) {
+	shard.storeWithCost(key, value, shard.costOf(value))
+}
+
+// Stores a value of the given cost with the default expiration of the
+// shard, must be called with the write lock held
+func (shard *shard[K, V]) storeWithCost(key K, value V, cost int64) {
+	shard.putWithCost(key, value, cost)
+	shard.expireByDefault(key)
+}
+
+// Gives a key just written the default expiration of the shard, must be
+// called with the write lock held
+func (shard *shard[K, V]) expireByDefault(key K) {
+	if shard.ttl > 0 {
+		shard.setExpiry(key, newExpiry(shard.ttl))
+	} else if shard.expires != nil {
+		delete(shard.expires, key)
+	}
+	if shard.deleted != nil {
+		delete(shard.deleted, key)
+	}
+}
+
+// Removes a key and its expiration, must be called with the write lock held
+func (shard *shard[K, V]) remove(key K) {
+	if shard.budget != nil {
+		if _, ok := shard.items[key]; ok {
+			shard.budget.addEntries(-1)
+		}
+	}
+	shard.own()
+	shard.touch(key)
+	delete(shard.versions, key)
+	delete(shard.items, key)
+	for _, ix := range shard.indexes {
+		ix.remove(key)
+	}
+	if shard.policy != nil {
+		shard.policy.Remove(key)
+	}
+	shard.uncharge(key)
+	if shard.expires != nil {
+		delete(shard.expires, key)
+	}
+	if shard.deleted != nil {
+		delete(shard.deleted, key)
+	}
+}
+
+// Removes a key like remove without logging it, for a key whose writes are
+// logged by the map it was forwarded to. Must be called with the write lock
+// held.
+func (shard *shard[K, V]) drop(key K) {
+	shard.remove(key)
+	delete(shard.touched, key)
+}
+
+// Removes every item, must be called with the write lock held
+func (shard *shard[K, V]) clear() {
+	if shard.wal != nil {
+		for key := range shard.items {
+			shard.touch(key)
+		}
+	}
+	if shard.budget != nil {
+		shard.budget.addEntries(-int64(len(shard.items)))
+		shard.budget.addCost(-shard.cost)
+	}
+	shard.release()
+	shard.items = make(map[K]V)
+	if shard.costs != nil {
+		shard.costs = make(map[K]int64)
+		shard.cost = 0
+	}
+	shard.expires = nil
+	shard.deleted = nil
+	shard.versions = nil
+	for _, ix := range shard.indexes {
+		ix.reset()
+	}
+	if shard.policy != nil {
+		shard.policy.Reset()
+	}
+}
+
+// Appends the items that are not expired to items under the read lock
+func (shard *shard[K, V]) appendItems(items []Entry[K, V]) []Entry[K, V] {
+	return shard.appendItemsWhere(items, nil)
+}
+
+// Appends the items that are not expired and match pred, or all of them if
+// pred is nil, to items under the read lock
+func (shard *shard[K, V]) appendItemsWhere(items []Entry[K, V], pred func(K, V) bool) []Entry[K, V] {
+	shard.RLock()
+	defer shard.RUnlock()
+	for key := range shard.items {
+		if value, ok := shard.lookup(key); ok && (pred == nil || pred(key, value)) {
+			items = append(items, Entry[K, V]{key, value})
+		}
+	}
+	return items
+}
+
+// Map is a thread safe map from keys of type K to values of type V.
+// Map keeps a table of shards with length of `shardCount`, each one a
+// built-in map guarded by its own RWMutex. Using a slice of shards instead of
+// a large map is to avoid lock bottlenecks.
+type Map[K comparable, V any] struct {
+	epoch       uint64 // last fence epoch, accessed atomically
+	visible     uint64 // last epoch whose writes are all visible
+	table       atomic.Pointer[shardTable[K, V]]
+	hash        func(key K) uint32
+	faults      atomic.Value // *Faults
+	keyMasker   atomic.Value // KeyMasker[K]
+	keyCipher   atomic.Pointer[KeyCipher[K]]
+	migration   atomic.Value // *Map[K, V] receiving migrated entries
+	softWindow  atomic.Int64 // time.Duration soft-deleted items are kept
+	sliding     atomic.Bool  // whether Get renews the TTL of entries
+	applyMu     sync.Mutex   // serializes ApplyBatch
+	appliedSeq  atomic.Uint64
+	onExpire    atomic.Pointer[func(key K, value V)]
+	rnd         atomic.Pointer[rand.Rand] // nil for the global source
+	rndMu       sync.Mutex                // serializes draws from rnd
+	unchanged   atomic.Pointer[func(old, new V) bool]
+	wal         atomic.Pointer[wal[K, V]]
+	keyLocks    atomic.Pointer[keyLocks[K]]
+	retired     atomic.Pointer[retiredStats]
+	computing   computing[K, V]
+	statsOn     atomic.Bool // whether operations are counted, see WithStats
+	lockFree    atomic.Bool // whether Get reads the published shard views
+	reshardMu   sync.Mutex  // serializes Reshard
+	maintenance maintenance
+	inflight    inflight
+}
+
+// shardTable is the slice of shards of a map, replaced as a whole by Reshard.
+type shardTable[K comparable, V any] struct {
+	shards []*shard[K, V]
+	mask   uint32 // len(shards) - 1, selecting a shard from a hash
+}
+
+// Entry is a pair of key and value
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Create a new Map with default shard count.
+func NewMap[K comparable, V any]() *Map[K, V] {
+	return NewMapWithShard[K, V](defaultShardCount)
+}
+
+// Create a new Map with default shard count holding a copy of items, each
+// shard sized for its share of them up front.
+func NewMapFrom[K comparable, V any](items map[K]V) *Map[K, V] {
+	m := NewMap[K, V]()
+	t := m.table.Load()
+	sizes := make([]int, len(t.shards))
+	for key := range items {
+		sizes[m.hash(key)&t.mask]++
+	}
+	// The map is not shared yet, so its shards are written without locking.
+	for i, shard := range t.shards {
+		shard.items = make(map[K]V, sizes[i])
+	}
+	for key, value := range items {
+		t.shards[m.hash(key)&t.mask].store(key, value)
+	}
+	return m
+}
+
+// Create a new Map with a shard count derived from runtime.GOMAXPROCS when
+// it is called: four shards per processor, rounded up to a power of 2, and at
+// least the default shard count, so writers on every processor rarely
+// contend for a shard.
+func NewMapAuto[K comparable, V any]() *Map[K, V] {
+	return NewMapWithShard[K, V](autoShardCount())
+}
+
+// Create a new Map with given shard count.
+// NOTE: shard count must be power of 2, default shard count will be used otherwise.
+func NewMapWithShard[K comparable, V any](shardCount uint32) *Map[K, V] {
+	return NewMapWithHasher[K, V](shardCount, nil)
+}
+
+// Create a new Map with given shard count, selecting the shard of a key with
+// the low bits of hash(key) instead of the default per-map seeded hash, for
+// example to keep the placement of an earlier version with HashBKDRString.
+// A nil hash uses the default.
+// NOTE: shard count must be power of 2, default shard count will be used otherwise.
+func NewMapWithHasher[K comparable, V any](shardCount uint32, hash func(key K) uint32) *Map[K, V] {
+	if !isPowerOfTwo(shardCount) {
+		shardCount = defaultShardCount
+	}
+	m := new(Map[K, V])
+	m.init(shardCount)
+	if hash != nil {
+		m.hash = hash
+	}
+	return m
+}
+
+// Sets up the shards of a new map
+func (m *Map[K, V]) init(shardCount uint32) {
+	m.hash = defaultHasher[K]()
+	m.table.Store(newShardTable[K, V](shardCount, &m.statsOn))
+}
+
+// Returns a table of empty shards counting operations when statsOn is set
+func newShardTable[K comparable, V any](shardCount uint32, statsOn *atomic.Bool) *shardTable[K, V] {
+	t := &shardTable[K, V]{shards: make([]*shard[K, V], shardCount), mask: shardCount - 1}
+	for i := range t.shards {
+		t.shards[i] = &shard[K, V]{items: make(map[K]V), id: shardIDs.Add(1), statsOn: statsOn}
+	}
+	return t
+}
+
+// Returns the shards of the map. Functions indexing the shards, or using
+// their number, must call it once, since Reshard may replace them.
+func (m *Map[K, V]) shards() []*shard[K, V] {
+	return m.table.Load().shards
+}
+
+// Returns the number of shards
+func (m *Map[K, V]) shardCount() uint32 {
+	return uint32(len(m.shards()))
+}
+
+// Returns the hasher used for shard selection of keys of type K, seeded with
+// a new random seed so each map spreads keys differently and keys crafted to
+// collide in one map do not collide in others. Integer keys are salted and
+// mixed with HashSplitMix, other keys are hashed with hash/maphash.
+func defaultHasher[K comparable]() func(key K) uint32 {
+	seed := maphash.MakeSeed()
+	salt := maphash.Comparable(seed, uint64(0))
+	var zero K
+	switch any(zero).(type) {
+	case uint32:
+		return func(key K) uint32 { return HashSplitMix(uint64(any(key).(uint32)) ^ salt) }
+	case uint64:
+		return func(key K) uint32 { return HashSplitMix(any(key).(uint64) ^ salt) }
+	case int:
+		return func(key K) uint32 { return HashSplitMix(uint64(any(key).(int)) ^ salt) }
+	case int64:
+		return func(key K) uint32 { return HashSplitMix(uint64(any(key).(int64)) ^ salt) }
+	case string:
+		return func(key K) uint32 { return uint32(maphash.String(seed, any(key).(string))) }
+	}
+	return func(key K) uint32 { return uint32(maphash.Comparable(seed, key)) }
+}
+
+// Find the index of the shard with the given key
+func (m *Map[K, V]) shardIndex(key K) uint32 {
+	return m.hash(key) & m.table.Load().mask
+}
+
+// Find the specific shard with the given key
+func (m *Map[K, V]) locate(key K) *shard[K, V] {
+	t := m.table.Load()
+	return t.shards[m.hash(key)&t.mask]
+}
+
+// Split keys into one group per shard, returned along with the shards they
+// belong to
+func (m *Map[K, V]) groupByShard(keys []K) ([]*shard[K, V], [][]K) {
+	t := m.table.Load()
+	groups := make([][]K, len(t.shards))
+	for _, key := range keys {
+		i := m.hash(key) & t.mask
+		groups[i] = append(groups[i], key)
+	}
+	return t.shards, groups
+}
+
+// Groups entries by the shard of their key, returns the shards along with
+// the groups
+func (m *Map[K, V]) groupEntries(entries []Entry[K, V]) ([]*shard[K, V], [][]Entry[K, V]) {
+	t := m.table.Load()
+	groups := make([][]Entry[K, V], len(t.shards))
+	for _, e := range entries {
+		i := m.hash(e.Key) & t.mask
+		groups[i] = append(groups[i], e)
+	}
+	return t.shards, groups
+}
+
+// Retrieves a value
+func (m *Map[K, V]) Get(key K) (value V, ok bool) {
+	if f := m.loadFaults(); f != nil {
+		if miss, _ := m.injectFaults(context.Background(), f, key); miss {
+			return value, false
+		}
+	}
+	return m.get(key)
+}
+
+// Lookup retrieves a value like Get, for callers propagating errors: the
+// returned error wraps ErrNotFound if the key is missing.
+func (m *Map[K, V]) Lookup(key K) (V, error) {
+	value, ok := m.Get(key)
+	if !ok {
+		return value, fmt.Errorf("%w: %v", ErrNotFound, key)
+	}
+	return value, nil
+}
+
+func (m *Map[K, V]) get(key K) (value V, ok bool) {
+	shard := m.locate(key)
+	if sliding := m.sliding.Load(); m.lockFree.Load() && !sliding && shard.policy == nil {
+		value, ok = shard.published.Load().lookup(key)
+	} else if sliding || shard.policy != nil {
+		shard.Lock()
+		value, ok = shard.lookup(key)
+		if ok {
+			if sliding {
+				shard.slide(key)
+			}
+			if shard.policy != nil {
+				shard.policy.Touch(key)
+			}
+		}
+		shard.Unlock()
+	} else {
+		shard.RLock()
+		value, ok = shard.lookup(key)
+		shard.RUnlock()
+	}
+	if !ok {
+		if hook := m.onExpire.Load(); hook != nil {
+			m.reap(shard, key, *hook)
+		}
+		if dst := m.forwardTo(shard); dst != nil {
+			value, ok = dst.Get(key)
+		}
+	}
+	m.countGet(shard, ok)
+	return
+}
+
+// GetMany retrieves the values of keys, as Get would for each, grouping them
+// by shard so every shard is locked only once. The returned map holds the
+// keys that were found.
+func (m *Map[K, V]) GetMany(keys []K) map[K]V {
+	if f := m.loadFaults(); f != nil {
+		m.delay(context.Background(), f)
+	}
+	found := make(map[K]V, len(keys))
+	sliding := m.sliding.Load()
+	shards, groups := m.groupByShard(keys)
+	var missed []K
+	for i, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		shard := shards[i]
+		write := sliding || shard.policy != nil
+		var view *shardView[K, V]
+		if m.lockFree.Load() && !write {
+			view = shard.published.Load()
+		} else if write {
+			shard.Lock()
+		} else {
+			shard.RLock()
+		}
+		missed = missed[:0]
+		for _, key := range group {
+			var value V
+			var ok bool
+			if view != nil {
+				value, ok = view.lookup(key)
+			} else if value, ok = shard.lookup(key); ok && write {
+				if sliding {
+					shard.slide(key)
+				}
+				if shard.policy != nil {
+					shard.policy.Touch(key)
+				}
+			}
+			if ok {
+				m.countGet(shard, true)
+				found[key] = value
+			} else {
+				missed = append(missed, key)
+			}
+		}
+		if write {
+			shard.Unlock()
+		} else if view == nil {
+			shard.RUnlock()
+		}
+
+		for _, key := range missed {
+			if hook := m.onExpire.Load(); hook != nil {
+				m.reap(shard, key, *hook)
+			}
+			var ok bool
+			if dst := m.forwardTo(shard); dst != nil {
+				var value V
+				if value, ok = dst.Get(key); ok {
+					found[key] = value
+				}
+			}
+			m.countGet(shard, ok)
+		}
+	}
+	return found
+}
+
+// Sets value with the given key
+func (m *Map[K, V]) Set(key K, value V) {
+	if f := m.loadFaults(); f != nil {
+		m.delay(context.Background(), f)
+	}
+	m.set(key, value)
+}
+
+func (m *Map[K, V]) set(key K, value V) {
+	shard := m.locate(key)
+	shard.Lock()
+	if dst := m.forwardTo(shard); dst != nil {
+		dst.Set(key, value)
+		shard.drop(key)
+		shard.Unlock()
+		return
+	}
+	if m.isUnchanged(shard, key, value) {
+		shard.Unlock()
+		return
+	}
+	shard.store(key, value)
+	shard.Unlock()
+}
+
+// SetMany sets the values of items, as Set would for each in order, grouping
+// them by shard so every shard is locked only once.
+func (m *Map[K, V]) SetMany(items []Entry[K, V]) {
+	if f := m.loadFaults(); f != nil {
+		m.delay(context.Background(), f)
+	}
+	shards, groups := m.groupEntries(items)
+	for i, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		shard := shards[i]
+		shard.Lock()
+		for _, e := range group {
+			if dst := m.forwardTo(shard); dst != nil {
+				dst.Set(e.Key, e.Value)
+				shard.drop(e.Key)
+				continue
+			}
+			if m.isUnchanged(shard, e.Key, e.Value) {
+				continue
+			}
+			shard.store(e.Key, e.Value)
+		}
+		shard.Unlock()
+	}
+}
+
+// GetOrSet returns the existing value for the key if present. Otherwise, it
+// sets and returns the given value. loaded is true if the value was loaded,
+// false if it was set. Both happen under a single shard lock.
+func (m *Map[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if actual, ok := shard.lookup(key); ok {
+		return actual, true
+	}
+	if dst := m.forwardTo(shard); dst != nil {
+		return dst.GetOrSet(key, value)
+	}
+	shard.store(key, value)
+	return value, false
+}
+
+// SetIfAbsent sets value for the key only if it is not present, under a
+// single shard lock, like Redis SET NX. Returns whether the value was set.
+func (m *Map[K, V]) SetIfAbsent(key K, value V) bool {
+	_, loaded := m.GetOrSet(key, value)
+	return !loaded
+}
+
+// SetIfPresent sets value for the key only if it is already present, under a
+// single shard lock, like Redis SET XX. The expiration of the entry is
+// cleared as with Set. Returns whether the value was set.
+func (m *Map[K, V]) SetIfPresent(key K, value V) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if _, ok := shard.lookup(key); !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.SetIfPresent(key, value)
+		}
+		return false
+	}
+	shard.store(key, value)
+	return true
+}
+
+// Swap stores value for the key like Set and returns the previous value, if
+// any, under a single shard lock. loaded reports whether the key was present.
+func (m *Map[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	previous, loaded = shard.lookup(key)
+	if !loaded {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.Swap(key, value)
+		}
+	}
+	shard.store(key, value)
+	return previous, loaded
+}
+
+// Update runs fn with the current value of the key, if any, while holding the
+// shard write lock, and stores the value it returns, so values can be read,
+// modified and written back without an external mutex. The key is removed if
+// fn returns keep false. Updating an entry keeps its expiration, and a new
+// entry gets the default TTL of the map. fn must not call methods of the map.
+func (m *Map[K, V]) Update(key K, fn func(old V, exists bool) (new V, keep bool)) {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	old, exists := shard.lookup(key)
+	if !exists {
+		if dst := m.forwardTo(shard); dst != nil {
+			dst.Update(key, fn)
+			return
+		}
+		// Drop an expired entry so its expiration is not kept.
+		shard.remove(key)
+	}
+	new, keep := fn(old, exists)
+	switch {
+	case !keep:
+		shard.remove(key)
+	case exists:
+		shard.put(key, new)
+	default:
+		shard.store(key, new)
+	}
+}
+
+// Upsert sets value for the key if it is absent, or else stores the result of
+// merging the existing value with it, under a single shard lock. Returns the
+// value stored. Merging keeps the expiration of the entry. merge must not
+// call methods of the map.
+func (m *Map[K, V]) Upsert(key K, value V, merge func(existing, incoming V) V) V {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	existing, ok := shard.lookup(key)
+	if !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.Upsert(key, value, merge)
+		}
+		shard.store(key, value)
+		return value
+	}
+	value = merge(existing, value)
+	shard.put(key, value)
+	return value
+}
+
+// CompareAndSwap sets the value of key to new only if its current value is
+// equal to old, under a single shard lock. Returns whether the value was
+// swapped. Panics if the values are not comparable, like ==.
+func (m *Map[K, V]) CompareAndSwap(key K, old, new V) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	current, ok := shard.lookup(key)
+	if !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.CompareAndSwap(key, old, new)
+		}
+		return false
+	}
+	if any(current) != any(old) {
+		return false
+	}
+	shard.put(key, new)
+	return true
+}
+
+// CompareAndDelete removes key only if its current value is equal to old,
+// under a single shard lock. Returns whether the key was removed. Panics if
+// the values are not comparable, like ==.
+func (m *Map[K, V]) CompareAndDelete(key K, old V) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	current, ok := shard.lookup(key)
+	if !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.CompareAndDelete(key, old)
+		}
+		return false
+	}
+	if any(current) != any(old) {
+		return false
+	}
+	shard.remove(key)
+	return true
+}
+
+// Removes an item
+func (m *Map[K, V]) Delete(key K) {
+	if f := m.loadFaults(); f != nil {
+		m.delay(context.Background(), f)
+	}
+	m.delete(key)
+}
+
+func (m *Map[K, V]) delete(key K) {
+	shard := m.locate(key)
+	if m.statsOn.Load() {
+		shard.stats.deletes.Add(1)
+	}
+	shard.Lock()
+	shard.remove(key)
+	if dst := m.forwardTo(shard); dst != nil {
+		dst.Delete(key)
+	}
+	shard.Unlock()
+}
+
+// DeleteMany removes keys, as Delete would for each, grouping them by shard
+// so every shard is locked only once. Returns the number of keys that were
+// present, not counting expired ones.
+func (m *Map[K, V]) DeleteMany(keys []K) int {
+	if f := m.loadFaults(); f != nil {
+		m.delay(context.Background(), f)
+	}
+	count := 0
+	shards, groups := m.groupByShard(keys)
+	for i, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		shard := shards[i]
+		if m.statsOn.Load() {
+			shard.stats.deletes.Add(uint64(len(group)))
+		}
+		shard.Lock()
+		for _, key := range group {
+			_, ok := shard.lookup(key)
+			shard.remove(key)
+			if dst := m.forwardTo(shard); dst != nil && !ok {
+				_, ok = dst.GetAndDelete(key)
+			}
+			if ok {
+				count++
+			}
+		}
+		shard.Unlock()
+	}
+	return count
+}
+
+// Removes the keys matching pred, one shard at a time, and returns the
+// number of them that were not expired. pred is called with the shard lock
+// held.
+func (m *Map[K, V]) deleteFunc(pred func(key K) bool) int {
+	count := 0
+	shards := m.shards()
+	for _, shard := range shards {
+		shard.Lock()
+		n := 0
+		for key := range shard.items {
+			if !pred(key) {
+				continue
+			}
+			if _, ok := shard.lookup(key); ok {
+				n++
+			}
+			shard.remove(key)
+		}
+		shard.Unlock()
+		if m.statsOn.Load() {
+			shard.stats.deletes.Add(uint64(n))
+		}
+		count += n
+	}
+	// Reshard retires all the shards before moving any entry.
+	if dst := m.forwardTo(shards[0]); dst != nil {
+		count += dst.deleteFunc(pred)
+	}
+	return count
+}
+
+// GetAndDelete removes the key and returns its value, if any, under a single
+// shard lock. ok reports whether the key was present.
+func (m *Map[K, V]) GetAndDelete(key K) (value V, ok bool) {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	value, ok = shard.lookup(key)
+	shard.remove(key)
+	if !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.GetAndDelete(key)
+		}
+	}
+	return value, ok
+}
+
+// Pop delete and return a random item in the cache. Shards are visited
+// starting from a random one, skipping expired items. Panics if the map has
+// no item that is not expired.
+func (m *Map[K, V]) Pop() (K, V) {
+	shards := m.shards()
+	start := m.randIntN(len(shards))
+	for i := range shards {
+		shard := shards[(start+i)%len(shards)]
+		shard.Lock()
+		for key := range shard.items {
+			if value, ok := shard.lookup(key); ok {
+				shard.remove(key)
+				shard.Unlock()
+				return key, value
+			}
+		}
+		shard.Unlock()
+	}
+	panic("syncmap: map is empty")
+}
+
+// PopInto removes up to cap(buf) items that are not expired and stores them
+// in buf, returning the number removed. Shards are visited starting from a
+// random one, and buf is reused so nothing is allocated. Read buf[:n].
+func (m *Map[K, V]) PopInto(buf []Entry[K, V]) int {
+	shards := m.shards()
+	buf = buf[:cap(buf)]
+	if len(buf) == 0 {
+		return 0
+	}
+
+	var (
+		n     = 0
+		count = len(shards)
+		start = m.randIntN(count)
+	)
+
+	for i := 0; i < count && n < len(buf); i++ {
+		shard := shards[(start+i)%count]
+		shard.Lock()
+		for key := range shard.items {
+			if n == len(buf) {
+				break
+			}
+			value, ok := shard.lookup(key)
+			if !ok {
+				continue
+			}
+			buf[n] = Entry[K, V]{key, value}
+			shard.remove(key)
+			n++
+		}
+		shard.Unlock()
+	}
+	return n
+}
+
+// PopWeighted removes and returns an entry chosen with probability
+// proportional to its weight. A shard is first chosen by the total weight of
+// its entries, then an entry within it under its write lock; writes to the
+// map between both steps may skew the probabilities slightly. Entries with a
+// weight of zero or less are never chosen. ok is false if no entry has a
+// positive weight. weight must not call methods of the map.
+func (m *Map[K, V]) PopWeighted(weight func(key K, value V) float64) (key K, value V, ok bool) {
+	shards := m.shards()
+	totals := make([]float64, len(shards))
+	for {
+		sum := 0.0
+		for i, shard := range shards {
+			shard.RLock()
+			totals[i] = shard.totalWeight(weight)
+			shard.RUnlock()
+			sum += totals[i]
+		}
+		if sum <= 0 {
+			return key, value, false
+		}
+
+		target := m.randFloat64() * sum
+		idx := len(totals) - 1
+		for i, total := range totals {
+			if target < total {
+				idx = i
+				break
+			}
+			target -= total
+		}
+
+		shard := shards[idx]
+		shard.Lock()
+		target = m.randFloat64() * shard.totalWeight(weight)
+		for k := range shard.items {
+			v, live := shard.lookup(k)
+			if !live {
+				continue
+			}
+			w := weight(k, v)
+			if w <= 0 {
+				continue
+			}
+			key, value, ok = k, v, true
+			if target < w {
+				break
+			}
+			target -= w
+		}
+		if ok {
+			shard.remove(key)
+		}
+		shard.Unlock()
+		if ok {
+			return key, value, true
+		}
+		// The shard was emptied in the meantime, choose again.
+	}
+}
+
+// Sums the positive weights of the live items, must be called with the lock held
+func (shard *shard[K, V]) totalWeight(weight func(key K, value V) float64) float64 {
+	total := 0.0
+	for key := range shard.items {
+		if value, ok := shard.lookup(key); ok {
+			if w := weight(key, value); w > 0 {
+				total += w
+			}
+		}
+	}
+	return total
+}
+
+// Whether Map has the given key
+func (m *Map[K, V]) Has(key K) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Returns the number of items that are not expired
+func (m *Map[K, V]) Size() int {
+	size := 0
+	for _, shard := range m.shards() {
+		shard.RLock()
+		size += shard.size()
+		shard.RUnlock()
+	}
+	return size
+}
+
+// Returns the number of items that are not expired, must be called with the
+// lock held
+func (shard *shard[K, V]) size() int {
+	size := len(shard.items)
+	if len(shard.expires) > 0 {
+		now := time.Now().UnixNano()
+		for key, e := range shard.expires {
+			if _, ok := shard.items[key]; ok && e.expired(now) {
+				size--
+			}
+		}
+	}
+	return size
+}
+
+// Returns the number of items of each shard, in shard order, including
+// expired ones not yet removed
+func (m *Map[K, V]) ShardSizes() []int {
+	shards := m.shards()
+	sizes := make([]int, len(shards))
+	for i, shard := range shards {
+		shard.RLock()
+		sizes[i] = len(shard.items)
+		shard.RUnlock()
+	}
+	return sizes
+}
+
+// ShrinkToFit rebuilds the storage of each shard sized to its current number
+// of items, since Go maps never give back memory after deletions, such as
+// after a Flush or heavy Delete traffic. Shards are rebuilt one at a time
+// under their write lock, so writers are only blocked on a single shard.
+func (m *Map[K, V]) ShrinkToFit() {
+	for _, shard := range m.shards() {
+		shard.Lock()
+		shard.shrink()
+		shard.Unlock()
+	}
+}
+
+// Copies items, expires and costs into maps of their size, must be called
+// with the write lock held
+func (shard *shard[K, V]) shrink() {
+	items := make(map[K]V, len(shard.items))
+	for key, value := range shard.items {
+		items[key] = value
+	}
+	var expires map[K]expiry
+	if len(shard.expires) > 0 {
+		expires = make(map[K]expiry, len(shard.expires))
+		for key, e := range shard.expires {
+			expires[key] = e
+		}
+	}
+	// The copies are not shared with forks.
+	shard.release()
+	shard.items, shard.expires = items, expires
+	if shard.costs != nil {
+		costs := make(map[K]int64, len(shard.costs))
+		for key, cost := range shard.costs {
+			costs[key] = cost
+		}
+		shard.costs = costs
+	}
+}
+
+// Wipes all items from the map
+func (m *Map[K, V]) Flush() int {
+	return m.FlushFunc(nil)
+}
+
+// FlushFunc wipes all items from the map like Flush, then calls fn with each
+// item that was not expired. Each shard is only locked to swap its storage
+// for an empty one, and fn runs without any lock held, so writers are not
+// blocked while fn processes the old contents. fn may be nil. Returns the
+// number of items removed.
+func (m *Map[K, V]) FlushFunc(fn func(key K, value V)) int {
+	size := 0
+	for _, shard := range m.shards() {
+		shard.Lock()
+		if fn != nil {
+			// Forks must not write in place to the maps handed to fn.
+			shard.own()
+		}
+		items, expires := shard.items, shard.expires
+		size += len(items)
+		shard.clear()
+		shard.Unlock()
+
+		if fn == nil {
+			continue
+		}
+		now := time.Now().UnixNano()
+		for key, value := range items {
+			if e, ok := expires[key]; ok && e.expired(now) {
+				continue
+			}
+			fn(key, value)
+		}
+	}
+	return size
+}
+
+// Returns a channel from which each key in the map can be read. The items of
+// each shard are copied under its read lock and sent after unlocking it, so
+// the consumer may call methods of the map while reading.
+func (m *Map[K, V]) IterKeys() <-chan K {
+	return m.IterKeysCtx(context.Background())
+}
+
+// Return a channel from which each item (key:value pair) in the map can be
+// read, see IterKeys
+func (m *Map[K, V]) IterItems() <-chan Entry[K, V] {
+	return m.IterItemsCtx(context.Background())
+}
+
+// Return a channel from which the items matching pred can be read, see
+// IterItems. pred is called under the shard's read lock, so only matching
+// items are copied, and it must not call methods of the map.
+func (m *Map[K, V]) IterWhere(pred func(key K, value V) bool) <-chan Entry[K, V] {
+	return m.IterWhereCtx(context.Background(), pred)
+}
+
+// Range calls fn for each item until fn returns false, in the style of
+// sync.Map. Shards are visited one at a time: the items of a shard are copied
+// under its read lock and fn is called without any lock held, so fn may call
+// methods of the map. Range does not see a consistent snapshot of the whole
+// map, and unlike IterItems it leaks nothing when stopped early.
+func (m *Map[K, V]) Range(fn func(key K, value V) bool) {
+	var items []Entry[K, V]
+	for _, shard := range m.shards() {
+		items = shard.appendItems(items[:0])
+		for _, item := range items {
+			if !fn(item.Key, item.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Returns all keys, read shard by shard under read locks
+func (m *Map[K, V]) KeysSlice() []K {
+	keys := make([]K, 0, m.Size())
+	for _, shard := range m.shards() {
+		shard.RLock()
+		for key := range shard.items {
+			if _, ok := shard.lookup(key); ok {
+				keys = append(keys, key)
+			}
+		}
+		shard.RUnlock()
+	}
+	return keys
+}
+
+// Returns all values, read shard by shard under read locks
+func (m *Map[K, V]) ValuesSlice() []V {
+	values := make([]V, 0, m.Size())
+	for _, shard := range m.shards() {
+		shard.RLock()
+		for key := range shard.items {
+			if value, ok := shard.lookup(key); ok {
+				values = append(values, value)
+			}
+		}
+		shard.RUnlock()
+	}
+	return values
+}
+
+// ToMap returns a copy of the items in a plain map, the reverse of NewMapFrom.
+// It is the same as Snapshot.
+func (m *Map[K, V]) ToMap() map[K]V {
+	return m.Snapshot()
+}
+
+// Snapshot returns a copy of the items in a plain map, which callers can
+// iterate, serialize or diff without any locking. Shards are copied one at
+// a time under their read lock.
+func (m *Map[K, V]) Snapshot() map[K]V {
+	items := make(map[K]V, m.Size())
+	for _, shard := range m.shards() {
+		shard.RLock()
+		for key := range shard.items {
+			if value, ok := shard.lookup(key); ok {
+				items[key] = value
+			}
+		}
+		shard.RUnlock()
+	}
+	return items
+}
@@ -0,0 +1,60 @@
+package syncmap
+
+import "testing"
+
+func Test_NewMap(t *testing.T) {
+	m := NewMap[string, int]()
+	if m.shardCount() != defaultShardCount {
+		t.Error("NewMap(): map's shard count is wrong")
+	}
+
+	var shardCount uint32 = 8
+	m2 := NewMapWithShard[string, int](shardCount)
+	if m2.shardCount() != shardCount {
+		t.Error("NewMapWithShard(): map's shard count is wrong")
+	}
+}
+
+func Test_MapTyped(t *testing.T) {
+	m := NewMap[string, int]()
+	v, ok := m.Get("missing")
+	if ok || v != 0 {
+		t.Error("Get should return the zero value for missing key")
+	}
+
+	for i, key := range []string{"a", "b", "c"} {
+		m.Set(key, i)
+	}
+	if v, ok := m.Get("b"); !ok || v != 1 {
+		t.Error("Get should return the typed value")
+	}
+	if m.Size() != 3 {
+		t.Error("map should have 3 items")
+	}
+
+	sum := 0
+	for item := range m.IterItems() {
+		sum += item.Value
+	}
+	if sum != 3 {
+		t.Error("IterItems should return every item")
+	}
+
+	m.Delete("a")
+	key, value := m.Pop()
+	if m.Has(key) || (key == "b" && value != 1) || (key == "c" && value != 2) {
+		t.Error("Pop should remove and return an item", key, value)
+	}
+	if m.Flush() != 1 || m.Size() != 0 {
+		t.Error("Flush should remove the remaining item")
+	}
+}
+
+func Test_MapStructKeys(t *testing.T) {
+	type point struct{ x, y int }
+	m := NewMap[point, string]()
+	m.Set(point{1, 2}, "a")
+	if v, ok := m.Get(point{1, 2}); !ok || v != "a" {
+		t.Error("Get should support comparable struct keys")
+	}
+}
@@ -0,0 +1,47 @@
+package syncmap
+
+// Merge sets every entry of other in the map, as Set would, overwriting the
+// values of keys present in both. See MergeWith.
+func (m *Map[K, V]) Merge(other *Map[K, V]) {
+	m.MergeWith(other, nil)
+}
+
+// MergeWith sets every entry of other in the map, resolving keys present in
+// both by storing resolve(key, existing, incoming) instead, which keeps the
+// expiration of the entry as Upsert does. A nil resolve keeps the incoming
+// values. The entries of other are copied one shard at a time under its
+// read lock, then set grouped by shard, each shard of the map locked once,
+// and every key is resolved and set under a single lock. resolve must not
+// call methods of the map.
+func (m *Map[K, V]) MergeWith(other *Map[K, V], resolve func(key K, existing, incoming V) V) {
+	shards, groups := m.groupEntries(other.items(Weak))
+	for i, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		shard := shards[i]
+		shard.Lock()
+		for _, e := range group {
+			existing, ok := shard.lookup(e.Key)
+			if !ok {
+				if dst := m.forwardTo(shard); dst != nil {
+					dst.Upsert(e.Key, e.Value, func(existing, incoming V) V {
+						if resolve == nil {
+							return incoming
+						}
+						return resolve(e.Key, existing, incoming)
+					})
+					continue
+				}
+			}
+			value := e.Value
+			if ok && resolve != nil {
+				value = resolve(e.Key, existing, value)
+				shard.put(e.Key, value)
+			} else {
+				shard.store(e.Key, value)
+			}
+		}
+		shard.Unlock()
+	}
+}
@@ -0,0 +1,54 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Merge64(t *testing.T) {
+	m := NewWithShard64(4)
+	other := NewWithShard64(8)
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+		other.Set(uint64(i+50), -i)
+	}
+
+	m.Merge(other)
+	if m.Size() != 150 {
+		t.Error("Merge should add the keys of the other map", m.Size())
+	}
+	if v, _ := m.Get(60); v != -10 {
+		t.Error("Merge should overwrite the keys present in both", v)
+	}
+	if v, _ := m.Get(10); v != 10 {
+		t.Error("Merge should keep the keys only in the map", v)
+	}
+}
+
+func Test_MergeWith64(t *testing.T) {
+	m := New64()
+	m.SetWithTTL(1, 1, time.Hour)
+	other := New64()
+	other.Set(1, 10)
+	other.Set(2, 20)
+
+	m.MergeWith(other, func(key uint64, existing, incoming interface{}) interface{} {
+		return existing.(int) + incoming.(int)
+	})
+	if v, _ := m.Get(1); v != 11 {
+		t.Error("MergeWith should resolve the keys present in both", v)
+	}
+	if ttl, _ := m.TTL(1); ttl <= 0 {
+		t.Error("a resolved entry should keep its expiration", ttl)
+	}
+	if v, _ := m.Get(2); v != 20 {
+		t.Error("MergeWith should add the keys only in the other map", v)
+	}
+
+	m.MergeWith(m, func(key uint64, existing, incoming interface{}) interface{} {
+		return existing.(int) + incoming.(int)
+	})
+	if v, _ := m.Get(2); v != 40 {
+		t.Error("a map should merge into itself", v)
+	}
+}
@@ -0,0 +1,233 @@
+package syncmap
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Number of entries Reshard moves at a time per shard
+const reshardBatch = 256
+
+// Migrate moves all entries of the map to dst, for example a map with a
+// different shard count, while both maps remain usable. Entries are moved at
+// most batch at a time per shard, sleeping throttle between batches.
+//
+// Once Migrate is called the map forwards to dst: Get falls through to dst
+// for keys that were already moved, and Set and Delete are applied to dst.
+// Entries written to dst directly are never overwritten by migrated ones.
+// Migrate returns the number of entries moved, after which the map is empty
+// and keeps forwarding to dst until callers switch over.
+func (m *Map[K, V]) Migrate(dst *Map[K, V], batch int, throttle time.Duration) int {
+	if dst == m {
+		panic("syncmap: cannot migrate a map to itself")
+	}
+	if batch <= 0 {
+		batch = 1
+	}
+	m.migration.Store(dst)
+	return m.moveTo(m.shards(), dst, batch, throttle)
+}
+
+// Moves the entries of shards to dst, at most batch at a time per shard,
+// sleeping throttle between batches, and returns the number moved. Writes to
+// the shards must already be forwarded to dst.
+func (m *Map[K, V]) moveTo(shards []*shard[K, V], dst *Map[K, V], batch int, throttle time.Duration) int {
+	// Moving a key is not a write to a log shared by both maps.
+	sharedLog := m.wal.Load() != nil && m.wal.Load() == dst.wal.Load()
+
+	moved := 0
+	for _, shard := range shards {
+		for {
+			shard.Lock()
+			n := 0
+			for key, value := range shard.items {
+				if n == batch {
+					break
+				}
+				e, hasExpiry := shard.expires[key]
+				// Insert into dst before removing, so concurrent Gets always
+				// find the entry in one of the two maps.
+				if _, ok := shard.lookup(key); ok && dst.adopt(key, value, e, hasExpiry, sharedLog) {
+					moved++
+				}
+				if sharedLog {
+					shard.drop(key)
+				} else {
+					shard.remove(key)
+				}
+				n++
+			}
+			remaining := len(shard.items)
+			shard.Unlock()
+
+			if remaining == 0 {
+				break
+			}
+			if throttle > 0 {
+				time.Sleep(throttle)
+			}
+		}
+	}
+	return moved
+}
+
+// Clone returns an independent copy of the map with the same shard count and
+// hash, each shard copied under its read lock, so concurrent writes may or
+// may not be part of the copy. Entries keep their expiration. Unlike Fork,
+// the storage is copied right away, so later writes to either map cost no
+// copy. The clone is otherwise a plain map, as a fork is.
+func (m *Map[K, V]) Clone() *Map[K, V] {
+	shards := m.shards()
+	dst := NewMapWithHasher[K, V](uint32(len(shards)), m.hash)
+	// dst is not shared yet, so its shards are written without locking.
+	for i, c := range dst.shards() {
+		s := shards[i]
+		s.RLock()
+		c.items = make(map[K]V, len(s.items))
+		for key := range s.items {
+			if value, ok := s.lookup(key); ok {
+				c.store(key, value)
+				if e, hasExpiry := s.expires[key]; hasExpiry {
+					c.setExpiry(key, e)
+				}
+			}
+		}
+		s.RUnlock()
+	}
+	return dst
+}
+
+// CloneWithShards returns a copy of the map with a different shard count,
+// rebuilt with one goroutine per shard of the map. Entries keep their
+// expiration. Each shard is copied under its read lock, so the clone is not
+// a consistent snapshot of the whole map if it is written concurrently; for
+// a switchover without losing writes, use Migrate.
+// The clone selects shards with the same hash function as the map.
+// NOTE: shard count must be power of 2, default shard count will be used otherwise.
+func (m *Map[K, V]) CloneWithShards(shardCount uint32) *Map[K, V] {
+	dst := NewMapWithHasher[K, V](shardCount, m.hash)
+	var wg sync.WaitGroup
+	for _, s := range m.shards() {
+		wg.Add(1)
+		go func(s *shard[K, V]) {
+			defer wg.Done()
+			s.RLock()
+			defer s.RUnlock()
+			for key := range s.items {
+				if value, ok := s.lookup(key); ok {
+					e, hasExpiry := s.expires[key]
+					dst.adopt(key, value, e, hasExpiry, false)
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+	return dst
+}
+
+// Returns the map the entries are migrated to, nil when not migrating
+func (m *Map[K, V]) migratingTo() *Map[K, V] {
+	dst, _ := m.migration.Load().(*Map[K, V])
+	return dst
+}
+
+// Returns the map that operations on a key of the shard, locked or read by
+// the caller, are forwarded to when the key is not in the shard: the map
+// Reshard moves the shard to, else the map being migrated to, else nil
+func (m *Map[K, V]) forwardTo(shard *shard[K, V]) *Map[K, V] {
+	if dst := shard.movedTo.Load(); dst != nil {
+		return dst
+	}
+	// A map sharing the table of m, as after Reshard, holds the shard itself.
+	if dst := m.migratingTo(); dst != nil && dst.table.Load() != m.table.Load() {
+		return dst
+	}
+	return nil
+}
+
+// Reshard changes the shard count of the map while it stays in use, to add
+// shards when writers contend. Entries are moved shard by shard into new
+// shards, at most a small batch at a time, as by Migrate, with writes
+// forwarded to the new shards meanwhile, so no write is lost and writers
+// of a shard only wait for one batch. The new shards then replace the old
+// ones. Iterations running concurrently may miss entries, and KeyRefs must
+// be created again after Reshard. Bounded maps, and maps with indexes or
+// soft-deleted items, cannot be resharded: the returned error wraps
+// ErrNotReshardable. It wraps ErrValidation for a shard count that is not a
+// power of 2 or a map being migrated.
+func (m *Map[K, V]) Reshard(shardCount uint32) error {
+	if !isPowerOfTwo(shardCount) {
+		return fmt.Errorf("%w: shard count %d is not a power of 2", ErrValidation, shardCount)
+	}
+	m.reshardMu.Lock()
+	defer m.reshardMu.Unlock()
+	if m.migratingTo() != nil {
+		return fmt.Errorf("%w: map is being migrated", ErrValidation)
+	}
+	old := m.table.Load()
+	if len(old.shards) == int(shardCount) {
+		return nil
+	}
+	var ttl time.Duration
+	for _, shard := range old.shards {
+		shard.RLock()
+		ttl = shard.ttl
+		unsupported := shard.policy != nil || shard.costs != nil || len(shard.indexes) > 0 || len(shard.deleted) > 0
+		shard.RUnlock()
+		if unsupported {
+			return fmt.Errorf("%w: bounded maps and maps with indexes or soft-deleted items are not supported", ErrNotReshardable)
+		}
+	}
+
+	// Writes forwarded to dst during the move behave as on the map, and
+	// are counted in the stats of the map.
+	dst := NewMapWithHasher[K, V](shardCount, m.hash)
+	for _, shard := range dst.shards() {
+		shard.statsOn = &m.statsOn
+	}
+	dst.unchanged.Store(m.unchanged.Load())
+	dst.onExpire.Store(m.onExpire.Load())
+	dst.useWAL(m.wal.Load())
+	dst.sliding.Store(m.sliding.Load())
+	dst.WithTTL(ttl)
+	if m.lockFree.Load() {
+		dst.WithLockFreeReads()
+	}
+
+	// The old shards forward to dst before their entries move, and keep
+	// forwarding after the swap to callers that located a key in them,
+	// including through the dst of an earlier Reshard, which shares them.
+	// Such callers may still count operations on the old shards, so their
+	// counters are retired rather than copied.
+	for _, shard := range old.shards {
+		shard.Lock()
+		shard.movedTo.Store(dst)
+		shard.Unlock()
+	}
+	m.moveTo(old.shards, dst, reshardBatch, 0)
+	m.table.Store(dst.table.Load())
+	m.retireStats(old.shards)
+	return nil
+}
+
+// Sets a migrated entry with its expiration unless the key is present,
+// returns whether the entry was set. The write is not logged if quiet, for
+// an entry moved between maps sharing a log.
+func (m *Map[K, V]) adopt(key K, value V, e expiry, hasExpiry, quiet bool) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if _, ok := shard.lookup(key); ok {
+		return false
+	}
+	shard.place(key, value, shard.costOf(value))
+	shard.expireByDefault(key)
+	if hasExpiry {
+		shard.setExpiry(key, e)
+	}
+	if quiet {
+		delete(shard.touched, key)
+	}
+	return true
+}
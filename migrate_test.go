@@ -0,0 +1,252 @@
+package syncmap
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Migrate64(t *testing.T) {
+	src := NewWithShard64(4)
+	dst := NewWithShard64(64)
+	for i := 0; i < 1000; i++ {
+		src.Set(uint64(i), i)
+	}
+	dst.Set(1, "newer")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			if v, ok := src.Get(uint64(i)); !ok || (i != 1 && v.(int) != i) {
+				t.Error("Get should find every key during migration", i)
+				return
+			}
+		}
+	}()
+
+	moved := src.Migrate(dst, 10, 0)
+	wg.Wait()
+
+	if moved != 999 {
+		t.Error("Migrate should return the number of moved entries", moved)
+	}
+	if src.Size() != 0 || dst.Size() != 1000 {
+		t.Error("Migrate should move every entry", src.Size(), dst.Size())
+	}
+	if v, _ := dst.Get(1); v.(string) != "newer" {
+		t.Error("Migrate should not overwrite entries of the destination")
+	}
+
+	src.Set(2000, 2000)
+	if !dst.Has(2000) || src.Size() != 0 {
+		t.Error("Set should be forwarded to the destination")
+	}
+	src.Delete(5)
+	if dst.Has(5) {
+		t.Error("Delete should be forwarded to the destination")
+	}
+	if v, ok := src.Get(7); !ok || v.(int) != 7 {
+		t.Error("Get should fall through to the destination")
+	}
+}
+
+func Test_MigrateForwardsTTLWrites(t *testing.T) {
+	src := NewWithShard64(4)
+	dst := NewWithShard64(64)
+	src.SetWithTTL(1, 1, time.Hour)
+	src.Set(2, 2)
+	src.AcquireLease(3, "a", time.Hour)
+	src.Migrate(dst, 10, 0)
+
+	if n := src.ExpireMany([]uint64{1, 2}, 2*time.Hour); n != 2 {
+		t.Error("ExpireMany should be forwarded", n)
+	}
+	if ttl, _ := dst.TTL(2); ttl < 119*time.Minute {
+		t.Error("ExpireMany should update the destination", ttl)
+	}
+	validated := false
+	if !src.RefreshIfStale(1, 0, func(interface{}) bool { validated = true; return true }) || !validated {
+		t.Error("RefreshIfStale should be forwarded")
+	}
+	if !src.RenewLease(3, "a", 2*time.Hour) || src.AcquireLease(3, "b", time.Hour) {
+		t.Error("leases should be forwarded")
+	}
+	if ttl, _ := dst.TTL(3); ttl < 119*time.Minute {
+		t.Error("RenewLease should update the destination", ttl)
+	}
+	if !src.ReleaseLease(3, "a") || dst.Has(3) {
+		t.Error("ReleaseLease should be forwarded")
+	}
+	if !src.AcquireLease(4, "a", time.Hour) || !dst.Has(4) {
+		t.Error("AcquireLease should be forwarded")
+	}
+}
+
+func Test_Clone64(t *testing.T) {
+	m := NewWithShard64(4)
+	for i := 0; i < 1000; i++ {
+		m.Set(uint64(i), i)
+	}
+	m.ExpireMany([]uint64{1}, time.Hour)
+
+	clone := m.Clone()
+	if clone.shardCount() != 4 || clone.Size() != 1000 {
+		t.Error("clone should hold every entry in the same shard count", clone.Size())
+	}
+	if ttl, _ := clone.TTL(1); ttl <= 0 {
+		t.Error("clone should keep expirations", ttl)
+	}
+	clone.Set(1, "changed")
+	m.Delete(2)
+	if v, _ := m.Get(1); v.(int) != 1 || !clone.Has(2) {
+		t.Error("the map and its clone should be independent", v)
+	}
+}
+
+func Test_CloneWithShards64(t *testing.T) {
+	m := NewWithShard64(4)
+	for i := 0; i < 1000; i++ {
+		m.Set(uint64(i), i)
+	}
+	m.ExpireMany([]uint64{1}, time.Hour)
+
+	clone := m.CloneWithShards(64)
+	if clone.shardCount() != 64 || clone.Size() != 1000 {
+		t.Error("clone should hold every entry in the new shard count", clone.Size())
+	}
+	if _, ok := clone.locate(1).expires[1]; !ok {
+		t.Error("clone should keep expirations")
+	}
+	clone.Set(1, "changed")
+	if v, _ := m.Get(1); v.(int) != 1 {
+		t.Error("the original map should not be affected by the clone", v)
+	}
+}
+
+func Test_Reshard(t *testing.T) {
+	m := NewWithShard64(4).WithStats()
+	for i := uint64(0); i < 5000; i++ {
+		m.Set(i, i)
+	}
+	m.SetWithTTL(10000, "ttl", time.Hour)
+
+	// Writers keep running while the map is resharded.
+	var wg sync.WaitGroup
+	for w := uint64(0); w < 4; w++ {
+		wg.Add(1)
+		go func(w uint64) {
+			defer wg.Done()
+			for i := uint64(0); i < 2000; i++ {
+				key := 100000 + w*10000 + i
+				m.Set(key, key)
+				if v, ok := m.Get(key); !ok || v != key {
+					t.Error("a write should be readable during Reshard", key, v, ok)
+					return
+				}
+				m.Delete(i * 2)
+			}
+		}(w)
+	}
+	if err := m.Reshard(64); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	if s := m.Stats(); s.Sets != 5000+1+8000 || s.Deletes != 8000 || s.Hits != 8000 || s.Misses != 0 {
+		t.Error("every operation should be counted once across Reshard", s)
+	}
+	if m.shardCount() != 64 {
+		t.Error("map should use the new shard count", m.shardCount())
+	}
+	for i := uint64(0); i < 5000; i++ {
+		if _, ok := m.Get(i); ok == (i%2 == 0 && i < 4000) {
+			t.Error("entries should be kept and deletes applied", i, ok)
+			break
+		}
+	}
+	for key := uint64(100000); key < 140000; key += 10000 {
+		if !m.Has(key) || !m.Has(key+1999) {
+			t.Error("writes made during Reshard should be kept", key)
+		}
+	}
+	if ttl, ok := m.TTL(10000); !ok || ttl < 59*time.Minute {
+		t.Error("entries should keep their expiration", ttl, ok)
+	}
+	m.Set(1, "after")
+	if v, _ := m.Get(1); v != "after" {
+		t.Error("map should be usable after Reshard", v)
+	}
+	if err := m.Reshard(8); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Stats(); s.Sets != 5000+1+8000+1 {
+		t.Error("stats should be kept across several Reshards", s.Sets)
+	}
+}
+
+func Test_ReshardConcurrentWriters(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		m := NewWithShard64(4)
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		for w := uint64(0); w < 4; w++ {
+			wg.Add(1)
+			go func(w uint64) {
+				defer wg.Done()
+				for i := uint64(0); i < 1000; i++ {
+					key := w*10000 + i
+					switch i % 4 {
+					case 0:
+						m.Set(key, key)
+					case 1:
+						m.SetWithTTL(key, key, time.Hour)
+					case 2:
+						m.GetOrSet(key, key)
+					case 3:
+						m.Update(key, func(interface{}, bool) (interface{}, bool) { return key, true })
+					}
+					m.Set(key+5000, key)
+					m.Delete(key + 5000)
+				}
+			}(w)
+		}
+		go func() {
+			for _, n := range []uint32{64, 8, 128} {
+				if err := m.Reshard(n); err != nil {
+					t.Error(err)
+				}
+			}
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("writers deadlocked with Reshard")
+		}
+		if m.Size() != 4000 {
+			t.Fatal("no write should be lost across Reshard", round, m.Size())
+		}
+	}
+}
+
+func Test_ReshardErrors(t *testing.T) {
+	if err := New64().Reshard(100); !errors.Is(err, ErrValidation) {
+		t.Error("a shard count that is not a power of 2 should be rejected", err)
+	}
+	if err := NewWithCapacity64(100).Reshard(64); !errors.Is(err, ErrNotReshardable) {
+		t.Error("bounded maps should be rejected", err)
+	}
+	m := New64().WithLockFreeReads()
+	m.Set(1, 1)
+	if err := m.Reshard(128); err != nil {
+		t.Fatal(err)
+	}
+	m.Set(2, 2)
+	if !m.Has(1) || !m.Has(2) {
+		t.Error("lock-free reads should see the new shards")
+	}
+}
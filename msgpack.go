@@ -0,0 +1,355 @@
+package syncmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// MsgpackCodec encodes each item as a MessagePack array of its key and
+// value, which is typically about half the size of JSON and faster to
+// encode. Nil, booleans, numbers, strings, byte slices, and slices, arrays
+// and maps of those are supported; other types, such as structs, fail to
+// encode.
+//
+// Decoded values have the types JSON decoding into interface{} would give,
+// except that integers decode as int64, or uint64 when too large, floats
+// keep their precision and byte strings decode as []byte. Values are then
+// converted to K and V when they are numbers or strings, so a SyncMap64
+// gets uint64 keys back, but an int value of a SyncMap64 comes back as
+// int64.
+type MsgpackCodec[K comparable, V any] struct{}
+
+// Returns an Encoder writing MessagePack to w
+func (MsgpackCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	var buf []byte
+	return EncoderFunc[K, V](func(key K, value V) error {
+		buf = append(buf[:0], 0x92) // fixarray of 2
+		var err error
+		if buf, err = appendMsgpack(buf, reflect.ValueOf(&key).Elem()); err != nil {
+			return err
+		}
+		if buf, err = appendMsgpack(buf, reflect.ValueOf(&value).Elem()); err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	})
+}
+
+// Returns a Decoder reading the items written by a MessagePack Encoder
+func (MsgpackCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return msgpackDecoder[K, V]{bufio.NewReader(r)}
+}
+
+type msgpackDecoder[K comparable, V any] struct {
+	r *bufio.Reader
+}
+
+func (d msgpackDecoder[K, V]) Decode() (key K, value V, err error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return key, value, err // io.EOF at the end of the input
+	}
+	if b != 0x92 {
+		return key, value, fmt.Errorf("syncmap: msgpack item is not a pair, got 0x%x", b)
+	}
+	k, err := readMsgpack(d.r)
+	if err == nil {
+		err = assignMsgpack(reflect.ValueOf(&key).Elem(), k)
+	}
+	if err != nil {
+		return key, value, unexpectedEOF(err)
+	}
+	v, err := readMsgpack(d.r)
+	if err == nil {
+		err = assignMsgpack(reflect.ValueOf(&value).Elem(), v)
+	}
+	return key, value, unexpectedEOF(err)
+}
+
+// Reports the end of the input inside an item as io.ErrUnexpectedEOF
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Appends the MessagePack encoding of v to b
+func appendMsgpack(b []byte, v reflect.Value) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return append(b, 0xc0), nil
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		return appendMsgpack(b, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgpackInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendMsgpackUint(b, v.Uint()), nil
+	case reflect.Float32:
+		return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(float32(v.Float()))), nil
+	case reflect.Float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v.Float())), nil
+	case reflect.String:
+		s := v.String()
+		b = appendMsgpackLen(b, len(s), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(b, s...), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b = appendMsgpackLen(b, v.Len(), 0, 0, 0xc4, 0xc5, 0xc6)
+			for i := 0; i < v.Len(); i++ {
+				b = append(b, byte(v.Index(i).Uint()))
+			}
+			return b, nil
+		}
+		b = appendMsgpackLen(b, v.Len(), 0x90, 16, 0, 0xdc, 0xdd)
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if b, err = appendMsgpack(b, v.Index(i)); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		b = appendMsgpackLen(b, v.Len(), 0x80, 16, 0, 0xde, 0xdf)
+		iter := v.MapRange()
+		for iter.Next() {
+			var err error
+			if b, err = appendMsgpack(b, iter.Key()); err != nil {
+				return b, err
+			}
+			if b, err = appendMsgpack(b, iter.Value()); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	}
+	return b, fmt.Errorf("syncmap: msgpack cannot encode %s", v.Type())
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return appendMsgpackUint(b, uint64(i))
+	case i >= -32:
+		return append(b, byte(i)) // negative fixint
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+func appendMsgpackUint(b []byte, u uint64) []byte {
+	switch {
+	case u < 128:
+		return append(b, byte(u)) // positive fixint
+	case u <= math.MaxUint8:
+		return append(b, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(u))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), u)
+}
+
+// Appends the header of a string, binary, array or map of length n: the
+// fix format if n is below fixMax, else the formats with 8, 16 or 32 bit
+// lengths, where a zero format means there is none
+func appendMsgpackLen(b []byte, n int, fix byte, fixMax int, f8, f16, f32 byte) []byte {
+	switch {
+	case n < fixMax:
+		return append(b, fix|byte(n))
+	case f8 != 0 && n <= math.MaxUint8:
+		return append(b, f8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, f16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, f32), uint32(n))
+}
+
+// Reads a MessagePack value
+func readMsgpack(r *bufio.Reader) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return readMsgpackMap(r, int(b&0x0f))
+	case b&0xf0 == 0x90:
+		return readMsgpackArray(r, int(b&0x0f))
+	case b&0xe0 == 0xa0:
+		s, err := readMsgpackBytes(r, int(b&0x1f))
+		return string(s), err
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackUint(r, 1<<(b-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackBytes(r, int(n))
+	case 0xca:
+		u, err := readMsgpackUint(r, 4)
+		return math.Float32frombits(uint32(u)), err
+	case 0xcb:
+		u, err := readMsgpackUint(r, 8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := readMsgpackUint(r, 1<<(b-0xcc))
+		if u > math.MaxInt64 {
+			return u, err
+		}
+		return int64(u), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		u, err := readMsgpackUint(r, size)
+		// Sign extend from size bytes.
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackUint(r, 1<<(b-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		s, err := readMsgpackBytes(r, int(n))
+		return string(s), err
+	case 0xdc, 0xdd:
+		n, err := readMsgpackUint(r, 2<<(b-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, int(n))
+	case 0xde, 0xdf:
+		n, err := readMsgpackUint(r, 2<<(b-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, int(n))
+	}
+	return nil, fmt.Errorf("syncmap: unsupported msgpack format 0x%x", b)
+}
+
+// Reads a big endian unsigned integer of size bytes
+func readMsgpackUint(r *bufio.Reader, size int) (uint64, error) {
+	var u uint64
+	for i := 0; i < size; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		u = u<<8 | uint64(b)
+	}
+	return u, nil
+}
+
+// Reads n bytes, growing the buffer as they arrive rather than trusting n
+func readMsgpackBytes(r *bufio.Reader, n int) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err == nil && len(b) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+func readMsgpackArray(r *bufio.Reader, n int) ([]interface{}, error) {
+	var a []interface{}
+	for i := 0; i < n; i++ {
+		v, err := readMsgpack(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		a = append(a, v)
+	}
+	if a == nil {
+		a = []interface{}{}
+	}
+	return a, nil
+}
+
+// Reads a map of n pairs, as a map[string]interface{} if all keys are
+// strings and as a map[interface{}]interface{} otherwise
+func readMsgpackMap(r *bufio.Reader, n int) (interface{}, error) {
+	pairs, err := readMsgpackArray(r, 2*n)
+	if err != nil {
+		return nil, err
+	}
+	byString := make(map[string]interface{}, n)
+	for i := 0; i < len(pairs); i += 2 {
+		s, ok := pairs[i].(string)
+		if !ok {
+			break
+		}
+		byString[s] = pairs[i+1]
+	}
+	if len(byString) == n {
+		return byString, nil
+	}
+	m := make(map[interface{}]interface{}, n)
+	for i := 0; i < len(pairs); i += 2 {
+		if pairs[i] != nil && !reflect.TypeOf(pairs[i]).Comparable() {
+			return nil, errors.New("syncmap: msgpack map key is not comparable")
+		}
+		m[pairs[i]] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// Sets dst to a decoded value, converting numbers and strings to the type
+// of dst
+func assignMsgpack(dst reflect.Value, v interface{}) error {
+	if v == nil {
+		dst.SetZero()
+		return nil
+	}
+	src := reflect.ValueOf(v)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	if isNumberKind(src.Kind()) && isNumberKind(dst.Kind()) ||
+		src.Kind() == reflect.String && dst.Kind() == reflect.String {
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("syncmap: msgpack cannot decode %s into %s", src.Type(), dst.Type())
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Float64
+}
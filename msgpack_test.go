@@ -0,0 +1,94 @@
+package syncmap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func Test_MsgpackValues(t *testing.T) {
+	values := []interface{}{
+		nil, true, false,
+		int64(0), int64(127), int64(128), int64(-1), int64(-32), int64(-33),
+		int64(math.MinInt16), int64(math.MinInt64), int64(70000), uint64(math.MaxUint64),
+		float32(1.5), 2.25, "", "short", string(make([]byte, 300)),
+		[]byte{1, 2, 3}, []interface{}{int64(1), "two"},
+		map[string]interface{}{"a": int64(1)},
+		map[interface{}]interface{}{int64(1): "one"},
+	}
+	var buf bytes.Buffer
+	codec := MsgpackCodec[uint64, interface{}]{}
+	enc := codec.NewEncoder(&buf)
+	for i, v := range values {
+		if err := enc.Encode(uint64(i), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dec := codec.NewDecoder(&buf)
+	for i, want := range values {
+		key, got, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != uint64(i) || !reflect.DeepEqual(got, want) {
+			t.Errorf("decoded %d: %#v, want %d: %#v", key, got, i, want)
+		}
+	}
+	if _, _, err := dec.Decode(); err != io.EOF {
+		t.Error("decoder should return io.EOF at the end", err)
+	}
+
+	if err := enc.Encode(1, struct{}{}); err == nil {
+		t.Error("structs should fail to encode")
+	}
+}
+
+func Test_MsgpackCodec(t *testing.T) {
+	m := NewString()
+	for i := 0; i < 100; i++ {
+		m.Set(string(rune('a'+i%26))+string(rune('0'+i/26)), map[string]interface{}{"n": i, "ok": true})
+	}
+
+	var packed, encoded bytes.Buffer
+	if err := m.Export(MsgpackCodec[string, interface{}]{}.NewEncoder(&packed)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Export(JSONCodec[string, interface{}]{}.NewEncoder(&encoded)); err != nil {
+		t.Fatal(err)
+	}
+	if packed.Len() >= encoded.Len() {
+		t.Error("msgpack should be smaller than JSON", packed.Len(), encoded.Len())
+	}
+
+	restored := NewString()
+	n, err := restored.Import(MsgpackCodec[string, interface{}]{}.NewDecoder(&packed))
+	if err != nil || n != 100 {
+		t.Fatal("import should set every item", n, err)
+	}
+	v, _ := restored.Get("c0")
+	if !reflect.DeepEqual(v, map[string]interface{}{"n": int64(2), "ok": true}) {
+		t.Error("imported value should match", v)
+	}
+
+	fromJSON := NewString()
+	if n, err := fromJSON.Import(JSONCodec[string, interface{}]{}.NewDecoder(&encoded)); err != nil || n != 100 {
+		t.Error("JSON import should set every item", n, err)
+	}
+}
+
+func Test_MsgpackTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	MsgpackCodec[string, string]{}.NewEncoder(&buf).Encode("key", "value")
+	data := buf.Bytes()
+	_, _, err := MsgpackCodec[string, string]{}.NewDecoder(bytes.NewReader(data[:len(data)-2])).Decode()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("truncated item should fail with io.ErrUnexpectedEOF", err)
+	}
+	_, _, err = MsgpackCodec[uint64, string]{}.NewDecoder(bytes.NewReader(data)).Decode()
+	if err == nil {
+		t.Error("string key should fail to decode into uint64")
+	}
+}
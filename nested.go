@@ -0,0 +1,61 @@
+package syncmap
+
+// Helpers for a Map whose values are themselves maps of the same type, such
+// as a SyncMap64 of tenants to per-tenant SyncMap64s. V must be able to hold
+// a *Map[K, V], which is the case for interface{} values.
+
+// Sets value with innerKey in the map stored at outerKey, creating the inner
+// map atomically if it does not exist yet.
+// Panics if the value stored at outerKey is not a *Map[K, V].
+func (m *Map[K, V]) SetPath(outerKey, innerKey K, value V) {
+	m.innerMap(outerKey).Set(innerKey, value)
+}
+
+// Retrieves the value of innerKey in the map stored at outerKey. ok is false
+// if either key is missing or the value at outerKey is not a *Map[K, V].
+func (m *Map[K, V]) GetPath(outerKey, innerKey K) (value V, ok bool) {
+	v, ok := m.Get(outerKey)
+	if !ok {
+		return value, false
+	}
+	inner, ok := any(v).(*Map[K, V])
+	if !ok {
+		return value, false
+	}
+	return inner.Get(innerKey)
+}
+
+// Removes innerKey from the map stored at outerKey, if any
+func (m *Map[K, V]) DeletePath(outerKey, innerKey K) {
+	v, ok := m.Get(outerKey)
+	if !ok {
+		return
+	}
+	if inner, ok := any(v).(*Map[K, V]); ok {
+		inner.Delete(innerKey)
+	}
+}
+
+// Returns the inner map stored at key, creating it under the shard lock so
+// that concurrent callers always share the same inner map.
+func (m *Map[K, V]) innerMap(key K) *Map[K, V] {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	if v, ok := shard.lookup(key); ok {
+		inner, ok := any(v).(*Map[K, V])
+		if !ok {
+			panic("syncmap: value is not a nested map")
+		}
+		return inner
+	}
+
+	inner := NewMap[K, V]()
+	value, ok := any(inner).(V)
+	if !ok {
+		panic("syncmap: values cannot hold a nested map")
+	}
+	shard.store(key, value)
+	return inner
+}
@@ -0,0 +1,53 @@
+package syncmap
+
+import (
+	"sync"
+	"testing"
+)
+
+func Test_SetPath64(t *testing.T) {
+	m := New64()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.SetPath(1, uint64(i), i)
+		}(i)
+	}
+	wg.Wait()
+
+	if m.Size() != 1 {
+		t.Error("SetPath should create a single inner map")
+	}
+	v, ok := m.Get(1)
+	if !ok || v.(*SyncMap64).Size() != 8 {
+		t.Error("inner map should hold every value set concurrently")
+	}
+}
+
+func Test_GetPath64(t *testing.T) {
+	m := New64()
+	if _, ok := m.GetPath(1, 2); ok {
+		t.Error("GetPath should return false for missing outer key")
+	}
+
+	m.SetPath(1, 2, "v")
+	v, ok := m.GetPath(1, 2)
+	if !ok || v.(string) != "v" {
+		t.Error("GetPath should return the value set by SetPath")
+	}
+	if _, ok := m.GetPath(1, 3); ok {
+		t.Error("GetPath should return false for missing inner key")
+	}
+
+	m.Set(5, "not a map")
+	if _, ok := m.GetPath(5, 2); ok {
+		t.Error("GetPath should return false when the value is not a map")
+	}
+
+	m.DeletePath(1, 2)
+	if _, ok := m.GetPath(1, 2); ok {
+		t.Error("DeletePath should remove the inner key")
+	}
+}
@@ -0,0 +1,148 @@
+package syncmap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenMetricsFamily describes how a map of numeric values, such as a map of
+// counters, is rendered as an OpenMetrics metric family.
+type OpenMetricsFamily[K comparable] struct {
+	Name string
+	Help string
+	// Type is the OpenMetrics type, "counter" or "gauge". Defaults to gauge.
+	Type string
+	// Labels maps a key to the labels of its sample. When nil, each sample
+	// has a single "key" label holding the exported key.
+	Labels func(key K) map[string]string
+}
+
+// WriteOpenMetrics renders the map as an OpenMetrics text exposition with a
+// single metric family, one sample per key that is not expired, in ascending
+// key order, or in ascending order of exported keys when keys are not ordered.
+// Values must be integers or floats, otherwise the returned error wraps
+// ErrValidation and nothing is written.
+func (m *Map[K, V]) WriteOpenMetrics(w io.Writer, f OpenMetricsFamily[K]) error {
+	typ := f.Type
+	if typ == "" {
+		typ = "gauge"
+	}
+	sample := f.Name
+	if typ == "counter" {
+		sample += "_total"
+	}
+
+	type point struct {
+		key   K
+		value string
+	}
+	var points []point
+	for _, shard := range m.shards() {
+		shard.RLock()
+		for key := range shard.items {
+			value, ok := shard.lookup(key)
+			if !ok {
+				continue
+			}
+			v, err := formatMetricValue(any(value))
+			if err != nil {
+				shard.RUnlock()
+				return fmt.Errorf("%w: key %s: %v", ErrValidation, m.ExportKey(key), err)
+			}
+			points = append(points, point{key, v})
+		}
+		shard.RUnlock()
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if c, ok := compareKeys(points[i].key, points[j].key); ok {
+			return c < 0
+		}
+		return m.ExportKey(points[i].key) < m.ExportKey(points[j].key)
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, typ)
+	if f.Help != "" {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, escapeMetricText(f.Help))
+	}
+	for _, p := range points {
+		var labels map[string]string
+		if f.Labels != nil {
+			labels = f.Labels(p.key)
+		} else {
+			labels = map[string]string{"key": m.ExportKey(p.key)}
+		}
+		fmt.Fprintf(bw, "%s%s %s\n", sample, formatMetricLabels(labels), p.value)
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+func formatMetricValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("value of type %T is not a number", value)
+}
+
+func formatMetricLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, escapeMetricLabel(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	metricTextEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	metricLabelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeMetricText(s string) string {
+	return metricTextEscaper.Replace(s)
+}
+
+func escapeMetricLabel(s string) string {
+	return metricLabelEscaper.Replace(s)
+}
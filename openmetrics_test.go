@@ -0,0 +1,59 @@
+package syncmap
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_WriteOpenMetrics64(t *testing.T) {
+	m := New64()
+	m.Set(2, 10)
+	m.Set(1, 2.5)
+
+	var buf bytes.Buffer
+	err := m.WriteOpenMetrics(&buf, OpenMetricsFamily[uint64]{
+		Name: "requests",
+		Help: "Requests by tenant",
+		Type: "counter",
+		Labels: func(key uint64) map[string]string {
+			return map[string]string{"tenant": "t" + strconv.FormatUint(key, 10), "zone": `a"b`}
+		},
+	})
+	if err != nil {
+		t.Fatal("WriteOpenMetrics should succeed", err)
+	}
+	expected := `# TYPE requests counter
+# HELP requests Requests by tenant
+requests_total{tenant="t1",zone="a\"b"} 2.5
+requests_total{tenant="t2",zone="a\"b"} 10
+# EOF
+`
+	if buf.String() != expected {
+		t.Error("unexpected exposition", buf.String())
+	}
+
+	buf.Reset()
+	m.Delete(1)
+	m.SetWithTTL(4, 4, time.Nanosecond) // expired entries are not exported
+	time.Sleep(time.Millisecond)
+	m.WriteOpenMetrics(&buf, OpenMetricsFamily[uint64]{Name: "size"})
+	expected = `# TYPE size gauge
+size{key="2"} 10
+# EOF
+`
+	if buf.String() != expected {
+		t.Error("unexpected exposition with default labels", buf.String())
+	}
+
+	m.Set(3, "three")
+	buf.Reset()
+	if err := m.WriteOpenMetrics(&buf, OpenMetricsFamily[uint64]{Name: "size"}); !errors.Is(err, ErrValidation) {
+		t.Error("WriteOpenMetrics should reject non numeric values", err)
+	}
+	if buf.Len() != 0 {
+		t.Error("nothing should be written on error")
+	}
+}
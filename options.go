@@ -0,0 +1,66 @@
+package syncmap
+
+import "time"
+
+// Option configures a SyncMap64 created by New64.
+type Option func(*options)
+
+// options holds the configuration collected from the Options given to New64
+type options struct {
+	shardCount uint32
+	hash       func(key uint64) uint32
+	ttl        time.Duration
+	capacity   int
+	stats      bool
+}
+
+// WithShards sets the shard count.
+// NOTE: shard count must be power of 2, default shard count will be used otherwise.
+func WithShards(shardCount uint32) Option {
+	return func(o *options) { o.shardCount = shardCount }
+}
+
+// WithHasher places each key in the shard selected by the low bits of
+// h(key), see NewWithHasher.
+func WithHasher(h func(key uint64) uint32) Option {
+	if h == nil {
+		panic("syncmap: hasher is nil")
+	}
+	return func(o *options) { o.hash = h }
+}
+
+// WithTTL expires the values written without an explicit TTL after ttl, see
+// Map.WithTTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) { o.ttl = ttl }
+}
+
+// WithCapacity bounds the map to about capacity items, evicting the least
+// recently used ones, see NewMapWithCapacity.
+func WithCapacity(capacity int) Option {
+	return func(o *options) { o.capacity = capacity }
+}
+
+// WithStats counts the operations on the map, see Map.WithStats.
+func WithStats() Option {
+	return func(o *options) { o.stats = true }
+}
+
+// Creates a new SyncMap64 configured by opts
+func newWithOptions64(opts []Option) *SyncMap64 {
+	o := options{shardCount: defaultShardCount}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	m := NewMapWithHasher[uint64, interface{}](o.shardCount, o.hash)
+	if o.capacity > 0 {
+		m.bound(o.capacity, NewLRU[uint64])
+	}
+	if o.ttl > 0 {
+		m.WithTTL(o.ttl)
+	}
+	if o.stats {
+		m.WithStats()
+	}
+	return m
+}
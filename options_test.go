@@ -0,0 +1,35 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_New64Options(t *testing.T) {
+	m := New64()
+	if m.shardCount() != defaultShardCount {
+		t.Error("New64 without options should use the default shard count", m.shardCount())
+	}
+
+	m = New64(WithShards(4), WithHasher(func(key uint64) uint32 { return uint32(key) }),
+		WithCapacity(8), WithTTL(time.Hour), WithStats())
+	if m.shardCount() != 4 || m.shardIndex(5) != 1 {
+		t.Error("WithShards and WithHasher should select the shards", m.shardCount(), m.shardIndex(5))
+	}
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+	if m.Size() > 8 {
+		t.Error("WithCapacity should bound the map", m.Size())
+	}
+	if ttl, ok := m.TTL(99); !ok || ttl <= 0 {
+		t.Error("WithTTL should expire the writes", ttl, ok)
+	}
+	if s := m.Stats(); s.Sets != 100 {
+		t.Error("WithStats should count the writes", s.Sets)
+	}
+
+	if New64(WithShards(3)).shardCount() != defaultShardCount {
+		t.Error("a shard count that is not a power of 2 should use the default")
+	}
+}
@@ -0,0 +1,85 @@
+package syncmap
+
+import (
+	"cmp"
+	"iter"
+	"reflect"
+	"slices"
+)
+
+// Compares keys a and b, returning -1, 0 or +1, and false if keys of type K
+// have no natural order. Integers, floats and strings are ordered, including
+// named types based on them.
+func compareKeys[K comparable](a, b K) (int, bool) {
+	switch x := any(a).(type) {
+	case uint64:
+		return cmp.Compare(x, any(b).(uint64)), true
+	case uint32:
+		return cmp.Compare(x, any(b).(uint32)), true
+	case int:
+		return cmp.Compare(x, any(b).(int)), true
+	case int64:
+		return cmp.Compare(x, any(b).(int64)), true
+	case string:
+		return cmp.Compare(x, any(b).(string)), true
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch va.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(va.Int(), vb.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(va.Uint(), vb.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(va.Float(), vb.Float()), true
+	case reflect.String:
+		return cmp.Compare(va.String(), vb.String()), true
+	}
+	return 0, false
+}
+
+// Whether keys of type K have a natural order
+func keysOrdered[K comparable]() bool {
+	var zero K
+	_, ok := compareKeys(zero, zero)
+	return ok
+}
+
+// Returns all keys in ascending order. Keys are collected as KeysSlice does,
+// so the result is not a consistent snapshot of a map written concurrently.
+// Panics if keys of type K are not ordered.
+func (m *Map[K, V]) KeysSorted() []K {
+	if !keysOrdered[K]() {
+		panic("syncmap: keys are not ordered")
+	}
+	keys := m.KeysSlice()
+	slices.SortFunc(keys, func(a, b K) int {
+		c, _ := compareKeys(a, b)
+		return c
+	})
+	return keys
+}
+
+// Returns an iterator over the items of the map in ascending key order.
+// All items are copied shard by shard when the loop starts, so the loop body
+// runs without any lock held. Panics if keys of type K are not ordered.
+func (m *Map[K, V]) AllSorted() iter.Seq2[K, V] {
+	if !keysOrdered[K]() {
+		panic("syncmap: keys are not ordered")
+	}
+	return func(yield func(K, V) bool) {
+		var items []Entry[K, V]
+		for _, shard := range m.shards() {
+			items = shard.appendItems(items)
+		}
+		slices.SortFunc(items, func(a, b Entry[K, V]) int {
+			c, _ := compareKeys(a.Key, b.Key)
+			return c
+		})
+		for _, item := range items {
+			if !yield(item.Key, item.Value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package syncmap
+
+import "sort"
+
+// PartitionKeys splits the current keys into n partitions of roughly equal
+// size, so that a pool of n workers can each export one without further
+// coordination. Partitions are made of whole shards, so every key of a shard
+// is in the same partition, and some partitions are empty if n is larger
+// than the shard count. Each shard is read under its lock, so keys written
+// concurrently may or may not be included.
+func (m *Map[K, V]) PartitionKeys(n int) [][]K {
+	shards := m.shards()
+	if n <= 0 {
+		panic("syncmap: partition count must be positive")
+	}
+	shardKeys := make([][]K, len(shards))
+	for i, shard := range shards {
+		shard.RLock()
+		keys := make([]K, 0, len(shard.items))
+		for key := range shard.items {
+			if _, ok := shard.lookup(key); ok {
+				keys = append(keys, key)
+			}
+		}
+		shard.RUnlock()
+		shardKeys[i] = keys
+	}
+
+	// Assign the largest shards first, each to the smallest partition.
+	sort.SliceStable(shardKeys, func(i, j int) bool {
+		return len(shardKeys[i]) > len(shardKeys[j])
+	})
+	partitions := make([][]K, n)
+	for _, keys := range shardKeys {
+		smallest := 0
+		for i := range partitions {
+			if len(partitions[i]) < len(partitions[smallest]) {
+				smallest = i
+			}
+		}
+		partitions[smallest] = append(partitions[smallest], keys...)
+	}
+	return partitions
+}
@@ -0,0 +1,41 @@
+package syncmap
+
+import "testing"
+
+func Test_PartitionKeys(t *testing.T) {
+	m := New64()
+	for i := 0; i < 10000; i++ {
+		m.Set(uint64(i), i)
+	}
+
+	partitions := m.PartitionKeys(3)
+	if len(partitions) != 3 {
+		t.Fatal("PartitionKeys should return n partitions", len(partitions))
+	}
+	seen := make(map[uint64]int)
+	for p, keys := range partitions {
+		if len(keys) < 3000 || len(keys) > 3700 {
+			t.Error("partitions should have roughly equal sizes", len(keys))
+		}
+		for _, key := range keys {
+			if _, dup := seen[key]; dup {
+				t.Error("key should be in a single partition", key)
+			}
+			seen[key] = p
+		}
+	}
+	if len(seen) != 10000 {
+		t.Error("every key should be in a partition", len(seen))
+	}
+	byShard := make(map[uint32]int)
+	for key, p := range seen {
+		if q, ok := byShard[m.shardIndex(key)]; ok && p != q {
+			t.Fatal("keys of a shard should be in the same partition", key)
+		}
+		byShard[m.shardIndex(key)] = p
+	}
+
+	if partitions := m.PartitionKeys(64); len(partitions[63]) != 0 {
+		t.Error("extra partitions should be empty")
+	}
+}
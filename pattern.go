@@ -0,0 +1,32 @@
+package syncmap
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DeletePrefix removes the keys of a string-keyed map starting with prefix,
+// such as "user:123:" to invalidate everything cached for a user. Shards are
+// scanned one at a time under their lock. Returns the number of keys
+// removed, not counting expired ones.
+func DeletePrefix[V any](m *Map[string, V], prefix string) int {
+	return m.deleteFunc(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// DeleteGlob removes the keys of a string-keyed map matching pattern, with
+// the syntax of path.Match, such as "user:*:session". Like in path.Match, *
+// and ? do not match a '/'. Shards are scanned one at a time under their
+// lock. Returns the number of keys removed, not counting expired ones, or an
+// error wrapping ErrValidation if the pattern is malformed.
+func DeleteGlob[V any](m *Map[string, V], pattern string) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	return m.deleteFunc(func(key string) bool {
+		matched, _ := path.Match(pattern, key)
+		return matched
+	}), nil
+}
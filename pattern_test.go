@@ -0,0 +1,42 @@
+package syncmap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func Test_DeletePrefix(t *testing.T) {
+	m := NewString()
+	for i := 0; i < 100; i++ {
+		m.Set(fmt.Sprintf("user:%d:name", i), i)
+		m.Set(fmt.Sprintf("user:%d:session", i), i)
+	}
+	if n := DeletePrefix(m, "user:1"); n != 22 {
+		t.Error("DeletePrefix should remove the keys with the prefix", n)
+	}
+	if m.Size() != 178 || m.Has("user:10:name") || !m.Has("user:2:name") {
+		t.Error("DeletePrefix should only remove the keys with the prefix", m.Size())
+	}
+}
+
+func Test_DeleteGlob(t *testing.T) {
+	m := NewString()
+	for i := 0; i < 100; i++ {
+		m.Set(fmt.Sprintf("user:%d:name", i), i)
+		m.Set(fmt.Sprintf("user:%d:session", i), i)
+	}
+	n, err := DeleteGlob(m, "user:*:session")
+	if err != nil || n != 100 {
+		t.Error("DeleteGlob should remove the matching keys", n, err)
+	}
+	if m.Size() != 100 || m.Has("user:5:session") || !m.Has("user:5:name") {
+		t.Error("DeleteGlob should only remove the matching keys", m.Size())
+	}
+	if n, _ := DeleteGlob(m, "user:?:name"); n != 10 {
+		t.Error("? should match a single character", n)
+	}
+	if _, err := DeleteGlob(m, "user:["); !errors.Is(err, ErrValidation) {
+		t.Error("a malformed pattern should be rejected", err)
+	}
+}
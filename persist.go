@@ -0,0 +1,193 @@
+package syncmap
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The format written by SaveTo is:
+//
+//	"SYNCMAP" followed by the format version, a single byte, 1 or 2
+//	a gob stream of:
+//	    a saveHeader holding the shard count of the saved map
+//	    one []savedEntry per shard, in shard order
+//
+// Each savedEntry holds a key, its value and, for entries with a TTL, the
+// unix time in nanoseconds when it expires and the TTL. Version 2, written
+// by maps with a KeyCipher, holds the encrypted key as a string instead.
+// Because the number of shards is known from the header, a truncated file
+// fails to load.
+const saveMagic = "SYNCMAP"
+
+const (
+	saveVersion          = 1
+	saveVersionEncrypted = 2
+)
+
+// The header of a saved map
+type saveHeader struct {
+	ShardCount uint32
+}
+
+// An entry of a saved map
+type savedEntry[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Expires int64 // unix nanoseconds, zero for no expiration
+	TTL     time.Duration
+}
+
+// SaveTo writes all items of the map to w, with their expiration, in the
+// format documented in persist.go. Shards are copied one at a time under
+// their read lock, so the snapshot is shard consistent. Keys are encrypted
+// if the map has a KeyCipher. Concrete types of interface{} values other
+// than the basic types must be registered with gob.Register.
+func (m *Map[K, V]) SaveTo(w io.Writer) error {
+	shards := m.shards()
+	c := m.keyCipher.Load()
+	version := saveVersion
+	if c != nil {
+		version = saveVersionEncrypted
+	}
+	if _, err := io.WriteString(w, saveMagic+string(rune(version))); err != nil {
+		return err
+	}
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(saveHeader{uint32(len(shards))}); err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		entries := shard.savedEntries()
+		if c == nil {
+			if err := enc.Encode(entries); err != nil {
+				return err
+			}
+			continue
+		}
+		sealed, err := sealEntries(c, entries)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(sealed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFrom reads items written by SaveTo from r and sets them in the map,
+// replacing present values and keeping other items. Entries that expired
+// since they were saved are skipped. The shard count of the saved map does
+// not need to match. Encrypted keys are decrypted with the KeyCipher of the
+// map; without one, the error wraps ErrValidation.
+func (m *Map[K, V]) LoadFrom(r io.Reader) error {
+	magic := make([]byte, len(saveMagic)+1)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return fmt.Errorf("syncmap: reading header: %w", err)
+	}
+	if string(magic[:len(saveMagic)]) != saveMagic {
+		return errors.New("syncmap: not a saved map")
+	}
+	c := m.keyCipher.Load()
+	switch version := magic[len(saveMagic)]; {
+	case version == saveVersion:
+		c = nil
+	case version == saveVersionEncrypted && c == nil:
+		return fmt.Errorf("%w: saved map has encrypted keys but the map has no KeyCipher", ErrValidation)
+	case version != saveVersionEncrypted:
+		return fmt.Errorf("syncmap: unsupported save format version %d", version)
+	}
+
+	dec := gob.NewDecoder(r)
+	var header saveHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("syncmap: reading header: %w", err)
+	}
+	now := time.Now().UnixNano()
+	for i := 0; i < int(header.ShardCount); i++ {
+		var entries []savedEntry[K, V]
+		if c == nil {
+			if err := dec.Decode(&entries); err != nil {
+				return fmt.Errorf("syncmap: reading shard %d: %w", i, err)
+			}
+		} else {
+			var sealed []savedEntry[string, V]
+			if err := dec.Decode(&sealed); err != nil {
+				return fmt.Errorf("syncmap: reading shard %d: %w", i, err)
+			}
+			var err error
+			if entries, err = openEntries(c, sealed); err != nil {
+				return fmt.Errorf("syncmap: reading shard %d: %w", i, err)
+			}
+		}
+		for _, e := range entries {
+			if e.Expires == 0 {
+				m.Set(e.Key, e.Value)
+			} else if e.Expires > now {
+				m.restore(e.Key, e.Value, expiry{at: e.Expires, ttl: e.TTL})
+			}
+		}
+	}
+	return nil
+}
+
+// Copies the items that are not expired with their expiration under the
+// read lock
+func (shard *shard[K, V]) savedEntries() []savedEntry[K, V] {
+	shard.RLock()
+	defer shard.RUnlock()
+	entries := make([]savedEntry[K, V], 0, len(shard.items))
+	for key := range shard.items {
+		if value, ok := shard.lookup(key); ok {
+			e := savedEntry[K, V]{Key: key, Value: value}
+			if exp, ok := shard.expires[key]; ok {
+				e.Expires, e.TTL = exp.at, exp.ttl
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Returns entries with their keys encrypted by c
+func sealEntries[K comparable, V any](c *KeyCipher[K], entries []savedEntry[K, V]) ([]savedEntry[string, V], error) {
+	sealed := make([]savedEntry[string, V], len(entries))
+	for i, e := range entries {
+		key, err := c.Encrypt(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		sealed[i] = savedEntry[string, V]{key, e.Value, e.Expires, e.TTL}
+	}
+	return sealed, nil
+}
+
+// Returns sealed with their keys decrypted by c
+func openEntries[K comparable, V any](c *KeyCipher[K], sealed []savedEntry[string, V]) ([]savedEntry[K, V], error) {
+	entries := make([]savedEntry[K, V], len(sealed))
+	for i, e := range sealed {
+		key, err := c.Decrypt(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = savedEntry[K, V]{key, e.Value, e.Expires, e.TTL}
+	}
+	return entries, nil
+}
+
+// Sets a value with the given expiration
+func (m *Map[K, V]) restore(key K, value V, e expiry) {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if dst := m.forwardTo(shard); dst != nil {
+		dst.restore(key, value, e)
+		shard.drop(key)
+		return
+	}
+	shard.store(key, value)
+	shard.setExpiry(key, e)
+}
@@ -0,0 +1,48 @@
+package syncmap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func Test_SaveLoad(t *testing.T) {
+	m := NewWithShard64(4)
+	for i := uint64(0); i < 100; i++ {
+		m.Set(i, int(i))
+	}
+	m.SetWithTTL(100, "short", 20*time.Millisecond)
+	m.SetWithTTL(101, "long", time.Hour)
+
+	var buf bytes.Buffer
+	if err := m.SaveTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	time.Sleep(40 * time.Millisecond)
+
+	restored := NewWithShard64(16)
+	restored.Set(1000, "kept")
+	if err := restored.LoadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Size() != 102 {
+		t.Error("loaded map should hold the saved items and its own", restored.Size())
+	}
+	if v, ok := restored.Get(42); !ok || v != 42 {
+		t.Error("loaded map should hold the values", v, ok)
+	}
+	if restored.Has(100) {
+		t.Error("entries expired since saving should be skipped")
+	}
+	if ttl, ok := restored.TTL(101); !ok || ttl <= 59*time.Minute {
+		t.Error("loaded entries should keep their expiration", ttl, ok)
+	}
+
+	if err := NewWithShard64(4).LoadFrom(bytes.NewReader(data[:len(data)-5])); err == nil {
+		t.Error("truncated data should fail to load")
+	}
+	if err := NewWithShard64(4).LoadFrom(bytes.NewReader([]byte("garbage!"))); err == nil {
+		t.Error("data without the header should fail to load")
+	}
+}
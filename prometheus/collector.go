@@ -0,0 +1,69 @@
+//go:build prometheus
+
+package syncmapprom
+
+import (
+	"strconv"
+
+	"github.com/DeanThompson/syncmap"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sizeDesc = prometheus.NewDesc("syncmap_items",
+		"Number of items in the map.", []string{"map"}, nil)
+	shardSizeDesc = prometheus.NewDesc("syncmap_shard_items",
+		"Number of items in a shard of the map.", []string{"map", "shard"}, nil)
+	hitRatioDesc = prometheus.NewDesc("syncmap_hit_ratio",
+		"Fraction of Gets that found the key, counted once stats are enabled.", []string{"map"}, nil)
+	hitsDesc = prometheus.NewDesc("syncmap_hits_total",
+		"Gets that found the key, counted once stats are enabled.", []string{"map"}, nil)
+	missesDesc = prometheus.NewDesc("syncmap_misses_total",
+		"Gets that did not find the key, counted once stats are enabled.", []string{"map"}, nil)
+	evictionsDesc = prometheus.NewDesc("syncmap_evictions_total",
+		"Items evicted by the policy of a bounded map.", []string{"map"}, nil)
+	expirationsDesc = prometheus.NewDesc("syncmap_expirations_total",
+		"Expired items removed, counted once stats are enabled.", []string{"map"}, nil)
+)
+
+// Collector is a prometheus.Collector exporting the size, shard sizes and
+// statistics of a map, labeled with the name of the map. Hits, misses and
+// expirations are only counted by maps with stats enabled, see
+// syncmap.Map.WithStats.
+type Collector[K comparable, V any] struct {
+	name string
+	m    *syncmap.Map[K, V]
+}
+
+// Returns a collector for the map m, labeled with the given name
+func NewCollector[K comparable, V any](name string, m *syncmap.Map[K, V]) *Collector[K, V] {
+	return &Collector[K, V]{name: name, m: m}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector[K, V]) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sizeDesc
+	ch <- shardSizeDesc
+	ch <- hitRatioDesc
+	ch <- hitsDesc
+	ch <- missesDesc
+	ch <- evictionsDesc
+	ch <- expirationsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector[K, V]) Collect(ch chan<- prometheus.Metric) {
+	total := 0
+	for i, size := range c.m.ShardSizes() {
+		total += size
+		ch <- prometheus.MustNewConstMetric(shardSizeDesc, prometheus.GaugeValue, float64(size), c.name, strconv.Itoa(i))
+	}
+	ch <- prometheus.MustNewConstMetric(sizeDesc, prometheus.GaugeValue, float64(total), c.name)
+
+	s := c.m.Stats()
+	ch <- prometheus.MustNewConstMetric(hitRatioDesc, prometheus.GaugeValue, s.HitRatio(), c.name)
+	ch <- prometheus.MustNewConstMetric(hitsDesc, prometheus.CounterValue, float64(s.Hits), c.name)
+	ch <- prometheus.MustNewConstMetric(missesDesc, prometheus.CounterValue, float64(s.Misses), c.name)
+	ch <- prometheus.MustNewConstMetric(evictionsDesc, prometheus.CounterValue, float64(s.Evictions), c.name)
+	ch <- prometheus.MustNewConstMetric(expirationsDesc, prometheus.CounterValue, float64(s.Expirations), c.name)
+}
@@ -0,0 +1,14 @@
+// Package syncmapprom exports the metrics of a syncmap.Map to Prometheus.
+//
+// It lives in its own package so that the core syncmap package does not
+// depend on the Prometheus client. The collector is built with the
+// prometheus build tag, which must be set along with adding
+// github.com/prometheus/client_golang to the build:
+//
+//	go build -tags prometheus
+//
+// Register a collector for each map to watch:
+//
+//	m := syncmap.New64().WithStats()
+//	prometheus.MustRegister(syncmapprom.NewCollector("sessions", m))
+package syncmapprom
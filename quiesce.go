@@ -0,0 +1,72 @@
+package syncmap
+
+import (
+	"context"
+	"sync"
+)
+
+// inflight counts the background work running against a map, such as
+// iterators, replica refreshers and warm-up loaders, so that Quiesce can
+// wait for it to drain.
+type inflight struct {
+	mu       sync.Mutex
+	count    int
+	draining bool
+	idle     chan struct{} // closed once draining with no work left
+}
+
+// Registers a unit of work, returns false once the map is quiescing
+func (f *inflight) begin() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.draining {
+		return false
+	}
+	f.count++
+	return true
+}
+
+// Marks a unit of work registered by begin as done
+func (f *inflight) end() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count--
+	if f.count == 0 && f.draining {
+		close(f.idle)
+	}
+}
+
+// Rejects new work and returns a channel closed once running work is done
+func (f *inflight) drain() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.draining {
+		f.draining = true
+		f.idle = make(chan struct{})
+		if f.count == 0 {
+			close(f.idle)
+		}
+	}
+	return f.idle
+}
+
+// Quiesce prepares the map for shutdown: it rejects new iterators, replicas,
+// warm-ups, auto flushes and janitors, and waits until the running ones are
+// done, so no background work backed by the map is still pending when it
+// returns nil. Iterators must be drained, and the others closed by their
+// owners. Returns the context error if ctx is done first. The map stays
+// usable for plain reads and writes, and rejects background work for good
+// once Quiesce has been called.
+//
+// Once quiescing, IterKeys and IterItems return closed channels, ReadReplica
+// returns a replica that is never refreshed, WithAutoFlush never flushes,
+// StartJanitor never sweeps and Warmup runs no loader and fails with
+// ErrMapClosed.
+func (m *Map[K, V]) Quiesce(ctx context.Context) error {
+	select {
+	case <-m.inflight.drain():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
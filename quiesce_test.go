@@ -0,0 +1,46 @@
+package syncmap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_Quiesce(t *testing.T) {
+	m := New64()
+	m.Set(1, 1)
+	m.Set(2, 2)
+
+	keys := m.IterKeys()
+	<-keys // the iterator is now blocked sending the second key
+	r := m.ReadReplica(time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.Quiesce(ctx); err != context.DeadlineExceeded {
+		t.Error("Quiesce should wait for running iterators and replicas", err)
+	}
+
+	if _, ok := <-m.IterItems(); ok {
+		t.Error("IterItems should return a closed channel once quiescing")
+	}
+	if w := m.Warmup(); !errors.Is(w.Err(), ErrMapClosed) {
+		t.Error("Warmup should be rejected once quiescing", w.Err())
+	}
+	if late := m.ReadReplica(time.Millisecond); late.Size() != 2 {
+		t.Error("ReadReplica should still return a snapshot once quiescing")
+	} else {
+		late.Close()
+	}
+
+	for range keys {
+	}
+	r.Close()
+	if err := m.Quiesce(context.Background()); err != nil {
+		t.Error("Quiesce should return once background work is done", err)
+	}
+	if !m.Has(1) {
+		t.Error("the map should stay usable after Quiesce")
+	}
+}
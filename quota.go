@@ -0,0 +1,116 @@
+package syncmap
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// NewStringMapWithQuotas creates a Map with string keys holding at most
+// about capacity items, like NewMapWithPolicy, with soft quotas per key
+// prefix. quotas maps prefixes such as "session:" to their share of the
+// capacity, from 0 to 1. A prefix may use more than its share while there is
+// room, but once it does its items are evicted before any other, so a single
+// runaway namespace cannot evict everything else. Keys belong to the longest
+// prefix they start with, and keys without a quota prefix are evicted by the
+// policy as usual. Quotas are enforced per shard. newPolicy may be nil to
+// evict the least recently used items.
+func NewStringMapWithQuotas[V any](capacity int, quotas map[string]float64, newPolicy func() EvictionPolicy[string]) *Map[string, V] {
+	if newPolicy == nil {
+		newPolicy = NewLRU[string]
+	}
+	m := NewMapWithPolicy[string, V](capacity, newPolicy)
+	prefixes := make([]string, 0, len(quotas))
+	for prefix := range quotas {
+		prefixes = append(prefixes, prefix)
+	}
+	// Longest first, so keys match their most specific prefix.
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, shard := range m.shards() {
+		q := &quotaPolicy{
+			all:       shard.policy,
+			prefixes:  prefixes,
+			limits:    make(map[string]int, len(quotas)),
+			newPolicy: m.seededPolicies(newPolicy),
+		}
+		for prefix, share := range quotas {
+			q.limits[prefix] = int(math.Ceil(share * float64(shard.capacity)))
+		}
+		q.Reset()
+		shard.policy = q
+	}
+	return m
+}
+
+// quotaPolicy wraps an eviction policy, preferring victims among the keys of
+// prefixes over their quota. It keeps one more policy per prefix to order
+// the keys of each prefix.
+type quotaPolicy struct {
+	all       EvictionPolicy[string]
+	prefixes  []string
+	limits    map[string]int
+	newPolicy func() EvictionPolicy[string]
+
+	byPrefix map[string]EvictionPolicy[string]
+	counts   map[string]int
+	keys     map[string]struct{} // keys with a quota prefix
+}
+
+// Returns the quota prefix of key, if any
+func (q *quotaPolicy) prefix(key string) (string, bool) {
+	for _, prefix := range q.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+func (q *quotaPolicy) Touch(key string) {
+	q.all.Touch(key)
+	prefix, ok := q.prefix(key)
+	if !ok {
+		return
+	}
+	if _, known := q.keys[key]; !known {
+		q.keys[key] = struct{}{}
+		q.counts[prefix]++
+	}
+	p := q.byPrefix[prefix]
+	if p == nil {
+		p = q.newPolicy()
+		q.byPrefix[prefix] = p
+	}
+	p.Touch(key)
+}
+
+func (q *quotaPolicy) Remove(key string) {
+	q.all.Remove(key)
+	if _, known := q.keys[key]; !known {
+		return
+	}
+	prefix, _ := q.prefix(key)
+	delete(q.keys, key)
+	q.counts[prefix]--
+	q.byPrefix[prefix].Remove(key)
+}
+
+func (q *quotaPolicy) Victim() string {
+	over, worst := "", 0
+	for prefix, count := range q.counts {
+		if excess := count - q.limits[prefix]; excess > worst {
+			over, worst = prefix, excess
+		}
+	}
+	if worst > 0 {
+		return q.byPrefix[over].Victim()
+	}
+	return q.all.Victim()
+}
+
+func (q *quotaPolicy) Reset() {
+	q.all.Reset()
+	q.byPrefix = make(map[string]EvictionPolicy[string])
+	q.counts = make(map[string]int)
+	q.keys = make(map[string]struct{})
+}
@@ -0,0 +1,51 @@
+package syncmap
+
+import (
+	"strconv"
+	"testing"
+)
+
+// Returns n keys with the given prefix in the first shard of m
+func firstShardStrings(m *Map[string, int], prefix string, n int) []string {
+	var keys []string
+	for i := 0; len(keys) < n; i++ {
+		key := prefix + strconv.Itoa(i)
+		if m.shardIndex(key) == 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func Test_NewStringMapWithQuotas(t *testing.T) {
+	// Each shard holds 10 items, at most 2 of them sessions before they are
+	// preferred victims.
+	m := NewStringMapWithQuotas[int](32*10, map[string]float64{"session:": 0.2}, nil)
+	sessions := firstShardStrings(m, "session:", 6)
+	users := firstShardStrings(m, "user:", 6)
+
+	for _, key := range sessions {
+		m.Set(key, 0)
+	}
+	for _, key := range users[:4] {
+		m.Set(key, 0)
+	}
+	if m.Size() != 10 {
+		t.Error("a prefix may exceed its quota while there is room", m.Size())
+	}
+
+	// Sessions are the most recently used, yet they are over quota.
+	for _, key := range sessions {
+		m.Get(key)
+	}
+	m.Set(users[4], 0)
+	m.Set(users[5], 0)
+	for _, key := range users {
+		if !m.Has(key) {
+			t.Error("items within quota should not be evicted first", key)
+		}
+	}
+	if n := m.Size(); n != 10 {
+		t.Error("shard should stay within capacity", n)
+	}
+}
@@ -0,0 +1,39 @@
+package syncmap
+
+import "math/rand/v2"
+
+// WithRand makes the map draw the random numbers used by Pop, PopInto,
+// PopWeighted, random eviction and fault injection from src instead of the global source of
+// math/rand/v2, so tests can be deterministic. src is only used under a
+// mutex, so it need not be safe for concurrent use. A nil src restores the
+// global source. Returns the map for chaining.
+func (m *Map[K, V]) WithRand(src rand.Source) *Map[K, V] {
+	if src == nil {
+		m.rnd.Store(nil)
+	} else {
+		m.rnd.Store(rand.New(src))
+	}
+	return m
+}
+
+// Returns a random int in [0, n)
+func (m *Map[K, V]) randIntN(n int) int {
+	r := m.rnd.Load()
+	if r == nil {
+		return rand.IntN(n)
+	}
+	m.rndMu.Lock()
+	defer m.rndMu.Unlock()
+	return r.IntN(n)
+}
+
+// Returns a random float64 in [0, 1)
+func (m *Map[K, V]) randFloat64() float64 {
+	r := m.rnd.Load()
+	if r == nil {
+		return rand.Float64()
+	}
+	m.rndMu.Lock()
+	defer m.rndMu.Unlock()
+	return r.Float64()
+}
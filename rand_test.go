@@ -0,0 +1,54 @@
+package syncmap
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func Test_WithRand(t *testing.T) {
+	misses := func() []bool {
+		m := New64().WithRand(rand.NewPCG(1, 2))
+		m.Set(1, 1)
+		m.InjectFaults(&Faults{MissProbability: 0.5})
+		var result []bool
+		for i := 0; i < 64; i++ {
+			result = append(result, m.Has(1))
+		}
+		return result
+	}
+	a, b := misses(), misses()
+	hits := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal("the same source should inject the same faults", i)
+		}
+		if a[i] {
+			hits++
+		}
+	}
+	if hits == 0 || hits == len(a) {
+		t.Error("faults should be drawn from the source", hits)
+	}
+
+	m := New64().WithRand(rand.NewPCG(1, 2)).WithRand(nil)
+	m.Set(1, 1)
+	if k, _ := m.Pop(); k != 1 {
+		t.Error("a nil source should restore the global source")
+	}
+}
+
+func Test_WithRandEviction(t *testing.T) {
+	survivors := func() *SyncMap64 {
+		// A fixed hasher, since the default one is salted per map.
+		m := NewMapWithHasher[uint64, interface{}](4, func(key uint64) uint32 { return uint32(key) })
+		m.bound(64, NewRandomEviction[uint64])
+		m.WithRand(rand.NewPCG(1, 2))
+		for i := uint64(0); i < 1000; i++ {
+			m.Set(i, i)
+		}
+		return m
+	}
+	if a, b := survivors(), survivors(); !a.Equal(b, nil) {
+		t.Error("the same source should evict the same keys", a.Size(), b.Size())
+	}
+}
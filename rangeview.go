@@ -0,0 +1,108 @@
+package syncmap
+
+import (
+	"fmt"
+	"iter"
+)
+
+// RangeView is a view of a Map restricted to the keys in [lo, hi].
+// Writes outside the interval are rejected, and reads, Size and iteration
+// only see keys inside it. The view shares storage with the map.
+type RangeView[K comparable, V any] struct {
+	m      *Map[K, V]
+	lo, hi K
+}
+
+// RangeView64 is a view of a SyncMap64 restricted to a key interval.
+type RangeView64 = RangeView[uint64, interface{}]
+
+// Returns a view of the map restricted to the keys in [lo, hi].
+// Panics if keys of type K are not ordered.
+func (m *Map[K, V]) RangeView(lo, hi K) *RangeView[K, V] {
+	if !keysOrdered[K]() {
+		panic("syncmap: keys are not ordered")
+	}
+	return &RangeView[K, V]{m: m, lo: lo, hi: hi}
+}
+
+// Whether key is inside the view's interval
+func (v *RangeView[K, V]) Contains(key K) bool {
+	lo, _ := compareKeys(v.lo, key)
+	hi, _ := compareKeys(key, v.hi)
+	return lo <= 0 && hi <= 0
+}
+
+// Retrieves a value, ok is false for keys outside the interval
+func (v *RangeView[K, V]) Get(key K) (value V, ok bool) {
+	if !v.Contains(key) {
+		return value, false
+	}
+	return v.m.Get(key)
+}
+
+// Sets value with the given key, the error wraps ErrValidation if key is
+// outside the interval
+func (v *RangeView[K, V]) Set(key K, value V) error {
+	if !v.Contains(key) {
+		return fmt.Errorf("%w: key %v outside range [%v, %v]", ErrValidation, key, v.lo, v.hi)
+	}
+	v.m.Set(key, value)
+	return nil
+}
+
+// Removes an item, keys outside the interval are left untouched
+func (v *RangeView[K, V]) Delete(key K) {
+	if v.Contains(key) {
+		v.m.Delete(key)
+	}
+}
+
+// Whether the view has the given key
+func (v *RangeView[K, V]) Has(key K) bool {
+	_, ok := v.Get(key)
+	return ok
+}
+
+// Returns the number of items inside the interval, not counting expired ones
+func (v *RangeView[K, V]) Size() int {
+	size := 0
+	for _, shard := range v.m.shards() {
+		shard.RLock()
+		for key := range shard.items {
+			if _, ok := shard.lookup(key); ok && v.Contains(key) {
+				size++
+			}
+		}
+		shard.RUnlock()
+	}
+	return size
+}
+
+// Calls fn for each item inside the interval until fn returns false, with
+// the guarantees of Map.Range
+func (v *RangeView[K, V]) Range(fn func(key K, value V) bool) {
+	inside := func(key K, _ V) bool { return v.Contains(key) }
+	var items []Entry[K, V]
+	for _, shard := range v.m.shards() {
+		items = shard.appendItemsWhere(items[:0], inside)
+		for _, item := range items {
+			if !fn(item.Key, item.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Returns an iterator over the items inside the interval, see Range
+func (v *RangeView[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		v.Range(yield)
+	}
+}
+
+// Returns an iterator over the keys inside the interval, see Range
+func (v *RangeView[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		v.Range(func(key K, _ V) bool { return yield(key) })
+	}
+}
@@ -0,0 +1,72 @@
+package syncmap
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_RangeView64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+
+	v := m.RangeView(10, 19)
+	if v.Size() != 10 {
+		t.Error("Size should only count keys inside the range", v.Size())
+	}
+	if _, ok := v.Get(5); ok {
+		t.Error("Get should reject keys outside the range")
+	}
+	if x, ok := v.Get(10); !ok || x.(int) != 10 {
+		t.Error("Get should return keys inside the range")
+	}
+
+	if err := v.Set(20, 20); !errors.Is(err, ErrValidation) {
+		t.Error("Set should reject keys outside the range", err)
+	}
+	if err := v.Set(19, -19); err != nil {
+		t.Error("Set should accept keys inside the range", err)
+	}
+	if x, _ := m.Get(19); x.(int) != -19 {
+		t.Error("the view should share storage with the map")
+	}
+
+	v.Delete(50)
+	if !m.Has(50) {
+		t.Error("Delete should ignore keys outside the range")
+	}
+
+	keys := 0
+	for key := range v.Keys() {
+		if !v.Contains(key) {
+			t.Error("Keys should only return keys inside the range", key)
+		}
+		keys++
+	}
+	items := 0
+	for key := range v.All() {
+		if !v.Contains(key) {
+			t.Error("All should only return keys inside the range", key)
+		}
+		items++
+	}
+	if keys != 10 || items != 10 {
+		t.Error("iteration should cover every key inside the range")
+	}
+	for range v.All() {
+		break
+	}
+
+	m.SetWithTTL(15, 15, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if v.Size() != 9 {
+		t.Error("Size should not count expired keys", v.Size())
+	}
+	for key := range v.Keys() {
+		if key == 15 {
+			t.Error("Keys should skip expired keys")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package syncmap
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// readMostlyShard backs a shard with a sync.Map, counting its entries
+// since sync.Map cannot report its length.
+type readMostlyShard struct {
+	items sync.Map
+	size  atomic.Int64
+}
+
+// ReadMostlyMap is a thread safe map backing each shard with a sync.Map
+// instead of a built-in map guarded by a RWMutex. Reads of keys that are
+// not being written take no lock, so for workloads that mostly read a
+// stable set of keys it scales better than Map; writes, and reads of keys
+// recently added, are slower. ReadMostlyMap has none of the TTL, index or
+// migration features of Map.
+type ReadMostlyMap[K comparable, V any] struct {
+	shardCount uint32
+	shards     []*readMostlyShard
+	hash       func(key K) uint32
+}
+
+var _ ConcurrentMap = (*ReadMostlyMap[uint64, interface{}])(nil)
+
+// Create a new ReadMostlyMap with given shard count.
+// NOTE: shard count must be power of 2, default shard count will be used otherwise.
+func NewReadMostlyMap[K comparable, V any](shardCount uint32) *ReadMostlyMap[K, V] {
+	if !isPowerOfTwo(shardCount) {
+		shardCount = defaultShardCount
+	}
+	m := &ReadMostlyMap[K, V]{
+		shardCount: shardCount,
+		shards:     make([]*readMostlyShard, shardCount),
+		hash:       defaultHasher[K](),
+	}
+	for i := range m.shards {
+		m.shards[i] = new(readMostlyShard)
+	}
+	return m
+}
+
+// Find the specific shard with the given key
+func (m *ReadMostlyMap[K, V]) locate(key K) *readMostlyShard {
+	return m.shards[m.hash(key)&(m.shardCount-1)]
+}
+
+// Retrieves a value
+func (m *ReadMostlyMap[K, V]) Get(key K) (value V, ok bool) {
+	v, ok := m.locate(key).items.Load(key)
+	if ok {
+		// A nil interface stored for an interface V fails the plain assertion.
+		value, _ = v.(V)
+	}
+	return value, ok
+}
+
+// Sets value with the given key
+func (m *ReadMostlyMap[K, V]) Set(key K, value V) {
+	shard := m.locate(key)
+	if _, loaded := shard.items.Swap(key, value); !loaded {
+		shard.size.Add(1)
+	}
+}
+
+// Sets value if the key is absent, returns the value present after the call
+// and whether it was already present
+func (m *ReadMostlyMap[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	shard := m.locate(key)
+	v, loaded := shard.items.LoadOrStore(key, value)
+	if !loaded {
+		shard.size.Add(1)
+	}
+	actual, _ = v.(V)
+	return actual, loaded
+}
+
+// Removes an item
+func (m *ReadMostlyMap[K, V]) Delete(key K) {
+	shard := m.locate(key)
+	if _, loaded := shard.items.LoadAndDelete(key); loaded {
+		shard.size.Add(-1)
+	}
+}
+
+// Whether ReadMostlyMap has the given key
+func (m *ReadMostlyMap[K, V]) Has(key K) bool {
+	_, ok := m.locate(key).items.Load(key)
+	return ok
+}
+
+// Returns the number of items
+func (m *ReadMostlyMap[K, V]) Size() int {
+	size := int64(0)
+	for _, shard := range m.shards {
+		size += shard.size.Load()
+	}
+	return int(size)
+}
+
+// Calls fn for each item until fn returns false. As with sync.Map, no lock
+// is held, fn may call methods of the map, and an item written during the
+// iteration may or may not be visited.
+func (m *ReadMostlyMap[K, V]) Range(fn func(key K, value V) bool) {
+	for _, shard := range m.shards {
+		more := true
+		shard.items.Range(func(k, v interface{}) bool {
+			key, _ := k.(K)
+			value, _ := v.(V)
+			more = fn(key, value)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
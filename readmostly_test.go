@@ -0,0 +1,66 @@
+package syncmap
+
+import (
+	"sync"
+	"testing"
+)
+
+func Test_ReadMostlyMap(t *testing.T) {
+	m := NewReadMostlyMap[string, int](4)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				m.Set(string(rune('a'+w))+string(rune('0'+i%10)), i)
+			}
+		}(w)
+	}
+	wg.Wait()
+	if m.Size() != 40 {
+		t.Error("map should have 40 items", m.Size())
+	}
+	if v, ok := m.Get("a9"); !ok || v != 99 {
+		t.Error("Set should overwrite an existing value", v, ok)
+	}
+
+	if v, loaded := m.GetOrSet("a9", 1); !loaded || v != 99 {
+		t.Error("GetOrSet should return the present value", v, loaded)
+	}
+	if v, loaded := m.GetOrSet("z", 1); loaded || v != 1 || m.Size() != 41 {
+		t.Error("GetOrSet should set an absent key", v, loaded, m.Size())
+	}
+
+	m.Delete("z")
+	m.Delete("z")
+	if m.Has("z") || m.Size() != 40 {
+		t.Error("Delete should remove the item once", m.Size())
+	}
+
+	n := 0
+	m.Range(func(key string, value int) bool {
+		n++
+		return n < 5
+	})
+	if n != 5 {
+		t.Error("Range should stop when fn returns false", n)
+	}
+}
+
+func Test_ReadMostlyMapNilValue(t *testing.T) {
+	m := NewReadMostlyMap[uint64, interface{}](4)
+	m.Set(1, nil)
+	if v, ok := m.Get(1); !ok || v != nil {
+		t.Error("a nil value should be stored", v, ok)
+	}
+	if v, loaded := m.GetOrSet(1, 2); !loaded || v != nil {
+		t.Error("GetOrSet should return the nil value", v, loaded)
+	}
+	m.Range(func(key uint64, value interface{}) bool {
+		if key != 1 || value != nil {
+			t.Error("Range should visit the nil value", key, value)
+		}
+		return true
+	})
+}
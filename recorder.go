@@ -0,0 +1,166 @@
+package syncmap
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Call is an operation on a ConcurrentMap recorded by a Recorder.
+type Call struct {
+	Seq   int         // order of the call among all recorded calls, from 0
+	Op    string      // "Get", "Set", "Delete", "Has" or "Size"
+	Key   uint64      // unset for Size
+	Value interface{} // value passed to Set or returned by Get
+	OK    bool        // result of Get and Has
+	Size  int         // result of Size
+	At    time.Time
+}
+
+// Response is a scripted result of a Get, Has or Size call. Value and OK are
+// the results of Get, OK the one of Has and Size the one of Size.
+type Response struct {
+	Value interface{}
+	OK    bool
+	Size  int
+}
+
+type scriptKey struct {
+	op  string
+	key uint64
+}
+
+// Recorder is a ConcurrentMap for tests of code using a map. It records
+// every call with its results, and serves calls from a backing map unless a
+// response was scripted with Respond. It is safe for concurrent use.
+type Recorder struct {
+	m       ConcurrentMap
+	mu      sync.Mutex
+	calls   []Call
+	scripts map[scriptKey][]Response
+}
+
+var _ ConcurrentMap = (*Recorder)(nil)
+
+// Create a Recorder serving calls from m, or from a new SyncMap64 if m is nil
+func NewRecorder(m ConcurrentMap) *Recorder {
+	if m == nil {
+		m = New64()
+	}
+	return &Recorder{m: m, scripts: make(map[scriptKey][]Response)}
+}
+
+// Respond scripts the results of the next calls of op on key, one response
+// per call, after which calls are served by the backing map again. op must
+// be "Get", "Has" or "Size", and key is ignored for Size.
+func (r *Recorder) Respond(op string, key uint64, responses ...Response) {
+	switch op {
+	case "Get", "Has":
+	case "Size":
+		key = 0
+	default:
+		panic(fmt.Sprintf("syncmap: cannot script responses of %q", op))
+	}
+	r.mu.Lock()
+	k := scriptKey{op, key}
+	r.scripts[k] = append(r.scripts[k], responses...)
+	r.mu.Unlock()
+}
+
+// Pops the next scripted response of op on key, if any
+func (r *Recorder) scripted(op string, key uint64) (Response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := scriptKey{op, key}
+	responses := r.scripts[k]
+	if len(responses) == 0 {
+		return Response{}, false
+	}
+	if len(responses) == 1 {
+		delete(r.scripts, k)
+	} else {
+		r.scripts[k] = responses[1:]
+	}
+	return responses[0], true
+}
+
+func (r *Recorder) record(c Call) {
+	r.mu.Lock()
+	c.Seq = len(r.calls)
+	c.At = time.Now()
+	r.calls = append(r.calls, c)
+	r.mu.Unlock()
+}
+
+// Returns a copy of the calls recorded so far, in order
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Returns the recorded calls of op, in order
+func (r *Recorder) CallsOf(op string) []Call {
+	var calls []Call
+	for _, c := range r.Calls() {
+		if c.Op == op {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Forgets the recorded calls and scripted responses
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.calls = nil
+	r.scripts = make(map[scriptKey][]Response)
+	r.mu.Unlock()
+}
+
+// Retrieves a value
+func (r *Recorder) Get(key uint64) (value interface{}, ok bool) {
+	if resp, has := r.scripted("Get", key); has {
+		value, ok = resp.Value, resp.OK
+	} else {
+		value, ok = r.m.Get(key)
+	}
+	r.record(Call{Op: "Get", Key: key, Value: value, OK: ok})
+	return
+}
+
+// Sets value with the given key
+func (r *Recorder) Set(key uint64, value interface{}) {
+	r.m.Set(key, value)
+	r.record(Call{Op: "Set", Key: key, Value: value})
+}
+
+// Removes an item
+func (r *Recorder) Delete(key uint64) {
+	r.m.Delete(key)
+	r.record(Call{Op: "Delete", Key: key})
+}
+
+// Whether the map has the given key
+func (r *Recorder) Has(key uint64) bool {
+	var ok bool
+	if resp, has := r.scripted("Has", key); has {
+		ok = resp.OK
+	} else {
+		ok = r.m.Has(key)
+	}
+	r.record(Call{Op: "Has", Key: key, OK: ok})
+	return ok
+}
+
+// Returns the number of items
+func (r *Recorder) Size() int {
+	var size int
+	if resp, has := r.scripted("Size", 0); has {
+		size = resp.Size
+	} else {
+		size = r.m.Size()
+	}
+	r.record(Call{Op: "Size", Size: size})
+	return size
+}
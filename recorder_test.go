@@ -0,0 +1,55 @@
+package syncmap
+
+import "testing"
+
+func Test_Recorder(t *testing.T) {
+	r := NewRecorder(nil)
+	r.Set(1, "a")
+	r.Respond("Get", 1, Response{Value: "scripted", OK: true}, Response{})
+	r.Respond("Size", 42, Response{Size: 10})
+
+	if v, ok := r.Get(1); !ok || v != "scripted" {
+		t.Error("Get should return the first scripted response", v)
+	}
+	if _, ok := r.Get(1); ok {
+		t.Error("Get should return the second scripted response")
+	}
+	if v, ok := r.Get(1); !ok || v != "a" {
+		t.Error("Get should use the backing map once the script is done", v)
+	}
+	if r.Size() != 10 || r.Size() != 1 {
+		t.Error("Size should be scripted once")
+	}
+	r.Delete(1)
+	if r.Has(1) {
+		t.Error("Delete should be applied to the backing map")
+	}
+
+	calls := r.Calls()
+	if len(calls) != 8 {
+		t.Fatal("every call should be recorded", calls)
+	}
+	for i, c := range calls {
+		if c.Seq != i || c.At.IsZero() {
+			t.Error("calls should be numbered and timestamped", c)
+		}
+	}
+	if c := calls[0]; c.Op != "Set" || c.Key != 1 || c.Value != "a" {
+		t.Error("wrong Set call", c)
+	}
+	if gets := r.CallsOf("Get"); len(gets) != 3 || gets[2].Value != "a" || !gets[2].OK {
+		t.Error("CallsOf should return the calls with their results", gets)
+	}
+
+	r.Reset()
+	if len(r.Calls()) != 0 {
+		t.Error("Reset should forget the calls")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Respond should panic for operations without results")
+		}
+	}()
+	r.Respond("Set", 1, Response{})
+}
@@ -0,0 +1,98 @@
+package syncmap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Replica is a read-only copy of a Map, refreshed in the background, for
+// readers that tolerate slightly stale data but must not contend with the
+// writers of the map. Reads never take a lock.
+type Replica[K comparable, V any] struct {
+	items     atomic.Pointer[map[K]V]
+	refreshed atomic.Int64 // unix nanoseconds of the last refresh
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+// ReadReplica returns a replica of the map refreshed every maxStaleness, so
+// its data is at most about maxStaleness old. Refreshes are skipped while
+// maintenance is paused. A maxStaleness below a millisecond is raised to
+// one. The replica must be closed when no longer used.
+func (m *Map[K, V]) ReadReplica(maxStaleness time.Duration) *Replica[K, V] {
+	r := &Replica[K, V]{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	r.refresh(m)
+	if !m.inflight.begin() {
+		close(r.done)
+		return r
+	}
+
+	go func() {
+		defer m.inflight.end()
+		defer close(r.done)
+		ticker := time.NewTicker(clampInterval(maxStaleness))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+			select {
+			case <-m.maintenance.allowed():
+				r.refresh(m)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+func (r *Replica[K, V]) refresh(m *Map[K, V]) {
+	items := m.Snapshot()
+	r.items.Store(&items)
+	r.refreshed.Store(time.Now().UnixNano())
+}
+
+// Retrieves a value from the replica
+func (r *Replica[K, V]) Get(key K) (value V, ok bool) {
+	value, ok = (*r.items.Load())[key]
+	return
+}
+
+// Whether the replica has the given key
+func (r *Replica[K, V]) Has(key K) bool {
+	_, ok := r.Get(key)
+	return ok
+}
+
+// Returns the number of items in the replica
+func (r *Replica[K, V]) Size() int {
+	return len(*r.items.Load())
+}
+
+// Calls fn for each item of the replica until fn returns false
+func (r *Replica[K, V]) Range(fn func(key K, value V) bool) {
+	for key, value := range *r.items.Load() {
+		if !fn(key, value) {
+			return
+		}
+	}
+}
+
+// Returns how long ago the replica was refreshed
+func (r *Replica[K, V]) Age() time.Duration {
+	return time.Since(time.Unix(0, r.refreshed.Load()))
+}
+
+// Stops refreshing the replica. The replica stays readable.
+func (r *Replica[K, V]) Close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.done
+}
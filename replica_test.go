@@ -0,0 +1,75 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ReadReplica(t *testing.T) {
+	m := New64()
+	m.Set(1, 1)
+
+	r := m.ReadReplica(5 * time.Millisecond)
+	defer r.Close()
+	if v, ok := r.Get(1); !ok || v.(int) != 1 {
+		t.Error("replica should hold the items of the map")
+	}
+
+	m.Set(2, 2)
+	deadline := time.Now().Add(time.Second)
+	for !r.Has(2) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !r.Has(2) || r.Size() != 2 {
+		t.Error("replica should be refreshed in the background")
+	}
+	if r.Age() > time.Second {
+		t.Error("Age should report the time since the last refresh")
+	}
+
+	count := 0
+	r.Range(func(key uint64, value interface{}) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Error("Range should stop when fn returns false")
+	}
+}
+
+func Test_ReadReplicaPaused(t *testing.T) {
+	m := New64()
+	m.PauseMaintenance()
+	r := m.ReadReplica(time.Millisecond)
+
+	m.Set(1, 1)
+	time.Sleep(20 * time.Millisecond)
+	if r.Has(1) {
+		t.Error("replica should not refresh while maintenance is paused")
+	}
+
+	m.ResumeMaintenance()
+	deadline := time.Now().Add(time.Second)
+	for !r.Has(1) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !r.Has(1) {
+		t.Error("replica should catch up after maintenance resumes")
+	}
+	r.Close()
+	r.Close()
+}
+
+func Test_ReadReplicaZeroStaleness(t *testing.T) {
+	m := New64()
+	r := m.ReadReplica(0)
+	defer r.Close()
+	m.Set(1, 1)
+	deadline := time.Now().Add(time.Second)
+	for !r.Has(1) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !r.Has(1) {
+		t.Error("a non-positive maxStaleness should refresh every millisecond")
+	}
+}
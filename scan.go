@@ -0,0 +1,80 @@
+package syncmap
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"slices"
+)
+
+// Seed of the hash ordering the keys of a shard for Scan
+var scanSeed = maphash.MakeSeed()
+
+// Scan returns a batch of about count items starting at cursor, and the
+// cursor to pass to the next call, in the style of the Redis SCAN command.
+// A scan starts with cursor 0 and is complete when the returned cursor is 0.
+//
+// The cursor is a position in a fixed order of all possible keys: by shard,
+// then by a hash of the key within the shard. Every key present for the
+// whole scan is therefore returned at least once, whatever is set or deleted
+// in between, and a key is returned more than once only when it is deleted
+// and set again. Keys set or deleted during the scan may or may not be
+// returned. Batches may hold more than count items when keys share a hash,
+// and fewer, possibly none, before the scan is complete.
+//
+// Each call holds the read lock of one shard at a time, only while copying
+// the items after the cursor, and sorts them without any lock held.
+func (m *Map[K, V]) Scan(cursor uint64, count int) (items []Entry[K, V], next uint64) {
+	shards := m.shards()
+	if count <= 0 {
+		count = 10
+	}
+	shardBits := uint(bits.TrailingZeros(uint(len(shards))))
+	type scanItem struct {
+		pos  uint64
+		item Entry[K, V]
+	}
+	// The position of a key: the shard index in the top bits and the
+	// key's hash in the remaining ones.
+	position := func(i int, key K) uint64 {
+		return uint64(i)<<(64-shardBits) | maphash.Comparable(scanSeed, key)>>shardBits
+	}
+
+	var candidates []scanItem
+	for i := int(cursor >> (64 - shardBits)); i < len(shards); i++ {
+		shard := shards[i]
+		candidates = candidates[:0]
+		shard.RLock()
+		for key := range shard.items {
+			if value, ok := shard.lookup(key); ok {
+				if pos := position(i, key); pos >= cursor {
+					candidates = append(candidates, scanItem{pos, Entry[K, V]{key, value}})
+				}
+			}
+		}
+		shard.RUnlock()
+
+		slices.SortFunc(candidates, func(a, b scanItem) int {
+			switch {
+			case a.pos < b.pos:
+				return -1
+			case a.pos > b.pos:
+				return 1
+			}
+			return 0
+		})
+		for j, c := range candidates {
+			// Keys sharing a position are returned together, since the
+			// cursor cannot point between them.
+			if len(items) >= count && c.pos != candidates[j-1].pos {
+				return items, c.pos
+			}
+			items = append(items, c.item)
+		}
+		if len(items) >= count {
+			// The rest of the shard is empty, continue with the next one.
+			return items, uint64(i+1) << (64 - shardBits)
+		}
+		cursor = uint64(i+1) << (64 - shardBits)
+	}
+	return items, 0
+}
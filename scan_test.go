@@ -0,0 +1,67 @@
+package syncmap
+
+import "testing"
+
+func Test_Scan(t *testing.T) {
+	for _, shardCount := range []uint32{1, 4, 32} {
+		m := NewWithShard64(shardCount)
+		for i := uint64(0); i < 1000; i++ {
+			m.Set(i, i)
+		}
+		seen := make(map[uint64]int)
+		cursor, calls := uint64(0), 0
+		for {
+			var items []Item64
+			items, cursor = m.Scan(cursor, 25)
+			for _, item := range items {
+				seen[item.Key]++
+			}
+			calls++
+			if cursor == 0 {
+				break
+			}
+		}
+		if len(seen) != 1000 {
+			t.Error("scan should return every key", shardCount, len(seen))
+		}
+		for key, n := range seen {
+			if n != 1 {
+				t.Error("scan should return a key once without churn", key, n)
+			}
+		}
+		if calls < 1000/25 {
+			t.Error("scan should return batches of about count items", calls)
+		}
+	}
+}
+
+func Test_ScanChurn(t *testing.T) {
+	m := NewWithShard64(8)
+	for i := uint64(0); i < 500; i++ {
+		m.Set(i, i)
+	}
+	seen := make(map[uint64]bool)
+	cursor, next := uint64(0), uint64(10000)
+	for {
+		var items []Item64
+		items, cursor = m.Scan(cursor, 20)
+		for _, item := range items {
+			seen[item.Key] = true
+		}
+		// Churn keys other than the stable ones in [0, 250).
+		for i := 0; i < 20; i++ {
+			m.Set(next, next)
+			m.Delete(next - 15)
+			next++
+		}
+		m.Delete(250 + next%250)
+		if cursor == 0 {
+			break
+		}
+	}
+	for i := uint64(0); i < 250; i++ {
+		if !seen[i] {
+			t.Error("scan should return every key present for the whole scan", i)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package syncmap
+
+import "reflect"
+
+// ConcurrentMap is the set of operations shared by the uint64-keyed map
+// implementations, so that they can be swapped or wrapped.
+type ConcurrentMap interface {
+	Get(key uint64) (value interface{}, ok bool)
+	Set(key uint64, value interface{})
+	Delete(key uint64)
+	Has(key uint64) bool
+	Size() int
+}
+
+var _ ConcurrentMap = (*SyncMap64)(nil)
+
+// Divergence describes an operation for which the shadow map returned a
+// different result than the primary map.
+type Divergence struct {
+	Op              string // "Get", "Has" or "Size"
+	Key             uint64 // unset for Size
+	Primary, Shadow interface{}
+}
+
+// Shadow mirrors every operation to a second map implementation and reports
+// results that differ, so a new implementation can be rolled out alongside
+// the one it replaces. Results are always served from the primary map.
+//
+// The two maps are not updated atomically, so operations racing on the same
+// key may be reported as divergent.
+type Shadow struct {
+	primary, shadow ConcurrentMap
+	report          func(Divergence)
+}
+
+var _ ConcurrentMap = (*Shadow)(nil)
+
+// Create a Shadow serving from primary and mirroring to shadow. report is
+// called synchronously for each divergence.
+func NewShadow(primary, shadow ConcurrentMap, report func(Divergence)) *Shadow {
+	return &Shadow{primary: primary, shadow: shadow, report: report}
+}
+
+// Retrieves a value from the primary map
+func (s *Shadow) Get(key uint64) (value interface{}, ok bool) {
+	value, ok = s.primary.Get(key)
+	shadowValue, shadowOK := s.shadow.Get(key)
+	if ok != shadowOK || !reflect.DeepEqual(value, shadowValue) {
+		s.report(Divergence{Op: "Get", Key: key, Primary: value, Shadow: shadowValue})
+	}
+	return
+}
+
+// Sets value with the given key in both maps
+func (s *Shadow) Set(key uint64, value interface{}) {
+	s.primary.Set(key, value)
+	s.shadow.Set(key, value)
+}
+
+// Removes an item from both maps
+func (s *Shadow) Delete(key uint64) {
+	s.primary.Delete(key)
+	s.shadow.Delete(key)
+}
+
+// Whether the primary map has the given key
+func (s *Shadow) Has(key uint64) bool {
+	ok := s.primary.Has(key)
+	if shadowOK := s.shadow.Has(key); ok != shadowOK {
+		s.report(Divergence{Op: "Has", Key: key, Primary: ok, Shadow: shadowOK})
+	}
+	return ok
+}
+
+// Returns the number of items in the primary map
+func (s *Shadow) Size() int {
+	size := s.primary.Size()
+	if shadowSize := s.shadow.Size(); size != shadowSize {
+		s.report(Divergence{Op: "Size", Primary: size, Shadow: shadowSize})
+	}
+	return size
+}
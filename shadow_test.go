@@ -0,0 +1,41 @@
+package syncmap
+
+import "testing"
+
+func Test_Shadow(t *testing.T) {
+	primary, shadow := New64(), New64()
+	var divergences []Divergence
+	s := NewShadow(primary, shadow, func(d Divergence) {
+		divergences = append(divergences, d)
+	})
+
+	s.Set(1, 1)
+	s.Set(2, 2)
+	s.Delete(2)
+	if v, ok := s.Get(1); !ok || v.(int) != 1 {
+		t.Error("Get should return the primary value")
+	}
+	s.Has(2)
+	s.Size()
+	if len(divergences) != 0 {
+		t.Error("mirrored operations should not diverge", divergences)
+	}
+
+	shadow.Set(1, 100)
+	shadow.Set(3, 3)
+	s.Get(1)
+	s.Has(3)
+	s.Size()
+	if len(divergences) != 3 {
+		t.Fatal("every divergence should be reported", divergences)
+	}
+	if d := divergences[0]; d.Op != "Get" || d.Key != 1 || d.Primary.(int) != 1 || d.Shadow.(int) != 100 {
+		t.Error("wrong Get divergence", d)
+	}
+	if d := divergences[1]; d.Op != "Has" || d.Key != 3 {
+		t.Error("wrong Has divergence", d)
+	}
+	if d := divergences[2]; d.Op != "Size" || d.Primary.(int) != 1 || d.Shadow.(int) != 2 {
+		t.Error("wrong Size divergence", d)
+	}
+}
@@ -0,0 +1,167 @@
+package syncmap
+
+import (
+	"container/heap"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// Sizer returns the size in bytes of a value. It is called with a shard lock
+// held, so it must be cheap and must not access the map.
+type Sizer[V any] func(value V) int64
+
+// SizeHistogram counts values by size. Counts[i] is the number of values
+// whose size s satisfies 2^(i-1) < s <= 2^i, with Counts[0] holding sizes
+// of 0 and 1 byte.
+type SizeHistogram struct {
+	Counts []int
+	Total  int64 // sum of all sizes
+}
+
+// Upper bound in bytes of the sizes counted in bucket i
+func (h SizeHistogram) Bound(i int) int64 {
+	return 1 << uint(i)
+}
+
+// SizedKey is a key with the size of its value.
+type SizedKey[K comparable] struct {
+	Key  K
+	Size int64
+}
+
+// Returns a histogram of the sizes of all values in the map, not counting
+// expired ones
+func (m *Map[K, V]) SizeHistogram(sizer Sizer[V]) SizeHistogram {
+	var h SizeHistogram
+	for _, shard := range m.shards() {
+		shard.RLock()
+		for key := range shard.items {
+			value, ok := shard.lookup(key)
+			if !ok {
+				continue
+			}
+			size := sizer(value)
+			i := 0
+			if size > 1 {
+				i = bits.Len64(uint64(size - 1))
+			}
+			for len(h.Counts) <= i {
+				h.Counts = append(h.Counts, 0)
+			}
+			h.Counts[i]++
+			h.Total += size
+		}
+		shard.RUnlock()
+	}
+	return h
+}
+
+// Returns the n keys with the largest values, largest first, skipping
+// expired entries
+func (m *Map[K, V]) LargestEntries(n int, sizer Sizer[V]) []SizedKey[K] {
+	if n <= 0 {
+		return nil
+	}
+	h := make(sizedKeyHeap[K], 0, n)
+	for _, shard := range m.shards() {
+		shard.RLock()
+		for key := range shard.items {
+			value, ok := shard.lookup(key)
+			if !ok {
+				continue
+			}
+			size := sizer(value)
+			if len(h) < n {
+				heap.Push(&h, SizedKey[K]{key, size})
+			} else if size > h[0].Size {
+				h[0] = SizedKey[K]{key, size}
+				heap.Fix(&h, 0)
+			}
+		}
+		shard.RUnlock()
+	}
+	sort.Slice(h, func(i, j int) bool { return h[i].Size > h[j].Size })
+	return h
+}
+
+// sizedKeyHeap is a min-heap of keys by size.
+type sizedKeyHeap[K comparable] []SizedKey[K]
+
+func (h sizedKeyHeap[K]) Len() int            { return len(h) }
+func (h sizedKeyHeap[K]) Less(i, j int) bool  { return h[i].Size < h[j].Size }
+func (h sizedKeyHeap[K]) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sizedKeyHeap[K]) Push(x interface{}) { *h = append(*h, x.(SizedKey[K])) }
+func (h *sizedKeyHeap[K]) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// PrefixSeparator separates the namespaces of string keys, as in
+// "user:42:profile".
+const PrefixSeparator = ":"
+
+// PrefixStat holds the number of entries and bytes used by a key prefix.
+type PrefixStat struct {
+	Prefix string
+	Count  int
+	Bytes  int64 // size of the keys, plus the values if a Sizer is given
+}
+
+// PrefixStats groups the keys of a string-keyed map by their first depth
+// namespaces, such as "user:" for depth 1 or "user:42:" for depth 2, and
+// returns the number of entries and bytes of each prefix, largest first,
+// not counting expired entries. Keys with fewer namespaces are grouped by
+// all the ones they have, keys without any under the empty prefix. sizer
+// may be nil to count the size of keys only.
+func PrefixStats[V any](m *Map[string, V], depth int, sizer Sizer[V]) []PrefixStat {
+	stats := make(map[string]*PrefixStat)
+	for _, shard := range m.shards() {
+		shard.RLock()
+		for key := range shard.items {
+			value, ok := shard.lookup(key)
+			if !ok {
+				continue
+			}
+			prefix := keyPrefix(key, depth)
+			stat := stats[prefix]
+			if stat == nil {
+				stat = &PrefixStat{Prefix: prefix}
+				stats[prefix] = stat
+			}
+			stat.Count++
+			stat.Bytes += int64(len(key))
+			if sizer != nil {
+				stat.Bytes += sizer(value)
+			}
+		}
+		shard.RUnlock()
+	}
+
+	result := make([]PrefixStat, 0, len(stats))
+	for _, stat := range stats {
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Bytes != result[j].Bytes {
+			return result[i].Bytes > result[j].Bytes
+		}
+		return result[i].Prefix < result[j].Prefix
+	})
+	return result
+}
+
+// Returns key up to and including its depth-th separator, or its last one
+func keyPrefix(key string, depth int) string {
+	end := 0
+	for i := 0; i < depth; i++ {
+		n := strings.Index(key[end:], PrefixSeparator)
+		if n < 0 {
+			break
+		}
+		end += n + len(PrefixSeparator)
+	}
+	return key[:end]
+}
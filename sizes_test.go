@@ -0,0 +1,90 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func byteLen(v interface{}) int64 {
+	return int64(len(v.([]byte)))
+}
+
+func Test_SizeHistogram64(t *testing.T) {
+	m := New64()
+	m.Set(1, make([]byte, 1))
+	m.Set(2, make([]byte, 2))
+	m.Set(3, make([]byte, 3))
+	m.Set(4, make([]byte, 4))
+	m.Set(5, make([]byte, 1000))
+	m.SetWithTTL(6, make([]byte, 5000), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	h := m.SizeHistogram(byteLen)
+	if h.Total != 1010 {
+		t.Error("Total should be the sum of all sizes", h.Total)
+	}
+	expected := map[int]int{0: 1, 1: 1, 2: 2, 10: 1}
+	for i, c := range h.Counts {
+		if c != expected[i] {
+			t.Error("wrong count in bucket", i, h.Bound(i), c)
+		}
+	}
+}
+
+func Test_LargestEntries64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), make([]byte, i))
+	}
+	m.SetWithTTL(100, make([]byte, 1000), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	largest := m.LargestEntries(3, byteLen)
+	if len(largest) != 3 {
+		t.Fatal("LargestEntries should return n entries")
+	}
+	for i, e := range largest {
+		if e.Key != uint64(99-i) || e.Size != int64(99-i) {
+			t.Error("LargestEntries should return the largest entries first", e)
+		}
+	}
+	if len(m.LargestEntries(200, byteLen)) != 100 {
+		t.Error("LargestEntries should return every entry when n exceeds size")
+	}
+}
+
+func Test_PrefixStats(t *testing.T) {
+	m := NewString()
+	m.Set("user:1:profile", "abc")
+	m.Set("user:2:profile", "abc")
+	m.Set("user:2", "abc")
+	m.Set("session:x", "abcdef")
+	m.Set("version", "1")
+	m.SetWithTTL("expired:1", "abc", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	stats := PrefixStats(m, 1, nil)
+	if len(stats) != 3 {
+		t.Fatal("keys should be grouped by their first namespace", stats)
+	}
+	if stats[0] != (PrefixStat{"user:", 3, 34}) {
+		t.Error("largest prefix should come first", stats[0])
+	}
+	if stats[2] != (PrefixStat{"", 1, 7}) {
+		t.Error("keys without namespace should use the empty prefix", stats[2])
+	}
+
+	sizer := func(v interface{}) int64 { return int64(len(v.(string))) }
+	for _, stat := range PrefixStats(m, 2, sizer) {
+		switch stat.Prefix {
+		case "user:1:", "user:2:":
+			if stat.Count != 1 || stat.Bytes != 17 {
+				t.Error("sizes of values should be counted", stat)
+			}
+		case "user:":
+			if stat.Count != 1 {
+				t.Error("shallower keys should keep their own prefix", stat)
+			}
+		}
+	}
+}
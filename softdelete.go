@@ -0,0 +1,92 @@
+package syncmap
+
+import "time"
+
+// tombstone is a soft-deleted item kept for a possible Restore.
+type tombstone[V any] struct {
+	value     V
+	expiry    expiry
+	hasExpiry bool
+	at        int64 // unix nanoseconds of the SoftDelete
+}
+
+// Sets how long soft-deleted items can be restored. Items past the window
+// are dropped by DeleteExpired and the janitor. A non-positive window, the
+// default, keeps them until PurgeSoftDeleted is called.
+func (m *Map[K, V]) SetSoftDeleteWindow(window time.Duration) {
+	m.softWindow.Store(int64(window))
+}
+
+// Whether a tombstone is past the soft-delete window at time now
+func (m *Map[K, V]) tombstoneExpired(t tombstone[V], now int64) bool {
+	window := m.softWindow.Load()
+	return window > 0 && now-t.at >= window
+}
+
+// Drops the tombstones of a shard past the soft-delete window, must be
+// called with the write lock held
+func (m *Map[K, V]) reapTombstones(shard *shard[K, V], now int64) {
+	if m.softWindow.Load() <= 0 {
+		return
+	}
+	for key, t := range shard.deleted {
+		if m.tombstoneExpired(t, now) {
+			delete(shard.deleted, key)
+		}
+	}
+}
+
+// SoftDelete hides an item from the map while keeping it so it can be
+// restored with Restore. Returns false if the key is missing.
+func (m *Map[K, V]) SoftDelete(key K) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	value, ok := shard.lookup(key)
+	if !ok {
+		return false
+	}
+	e, hasExpiry := shard.expires[key]
+	shard.remove(key)
+	now := time.Now().UnixNano()
+	m.reapTombstones(shard, now)
+	if shard.deleted == nil {
+		shard.deleted = make(map[K]tombstone[V])
+	}
+	shard.deleted[key] = tombstone[V]{value, e, hasExpiry, now}
+	return true
+}
+
+// Restore brings back a soft-deleted item, unless the key was set again or
+// the soft-delete window has passed. Returns whether the item was restored.
+func (m *Map[K, V]) Restore(key K) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	t, ok := shard.deleted[key]
+	if !ok {
+		return false
+	}
+	delete(shard.deleted, key)
+	if m.tombstoneExpired(t, time.Now().UnixNano()) {
+		return false
+	}
+	shard.put(key, t.value)
+	if t.hasExpiry {
+		shard.setExpiry(key, t.expiry)
+	}
+	return true
+}
+
+// Permanently removes all soft-deleted items, including those still within
+// the soft-delete window, returns how many were removed
+func (m *Map[K, V]) PurgeSoftDeleted() int {
+	count := 0
+	for _, shard := range m.shards() {
+		shard.Lock()
+		count += len(shard.deleted)
+		shard.deleted = nil
+		shard.Unlock()
+	}
+	return count
+}
@@ -0,0 +1,76 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_SoftDelete(t *testing.T) {
+	m := New64()
+	if m.SoftDelete(1) {
+		t.Error("SoftDelete should return false for missing key")
+	}
+
+	m.Set(1, 1)
+	m.Set(2, 2)
+	if !m.SoftDelete(1) || m.Has(1) {
+		t.Error("SoftDelete should hide the item")
+	}
+	if m.Size() != 1 {
+		t.Error("soft-deleted items should not be counted")
+	}
+	if !m.Restore(1) {
+		t.Error("Restore should bring back the item")
+	}
+	if v, ok := m.Get(1); !ok || v.(int) != 1 {
+		t.Error("restored item should have its value")
+	}
+	if m.Restore(1) {
+		t.Error("Restore should return false for an item that is not deleted")
+	}
+
+	m.SoftDelete(1)
+	m.Set(1, 10)
+	if m.Restore(1) {
+		t.Error("Restore should not overwrite a key that was set again")
+	}
+
+	m.SoftDelete(1)
+	m.SoftDelete(2)
+	if m.PurgeSoftDeleted() != 2 || m.Restore(2) {
+		t.Error("PurgeSoftDeleted should remove soft-deleted items for good")
+	}
+}
+
+func Test_SoftDeleteWindow(t *testing.T) {
+	m := New64()
+	m.SetSoftDeleteWindow(time.Millisecond)
+	m.Set(1, 1)
+	m.SoftDelete(1)
+	time.Sleep(5 * time.Millisecond)
+	if m.Restore(1) || m.Has(1) {
+		t.Error("Restore should fail after the soft-delete window")
+	}
+}
+
+func Test_SoftDeleteReap(t *testing.T) {
+	m := New64()
+	m.SetSoftDeleteWindow(time.Millisecond)
+	m.Set(1, 1)
+	m.Set(2, 2)
+	m.SoftDelete(1)
+	m.SoftDelete(2)
+	time.Sleep(5 * time.Millisecond)
+	m.DeleteExpired()
+	if m.PurgeSoftDeleted() != 0 {
+		t.Error("DeleteExpired should drop soft-deleted items past the window")
+	}
+
+	m.SetSoftDeleteWindow(time.Hour)
+	m.Set(3, 3)
+	m.SoftDelete(3)
+	m.DeleteExpired()
+	if m.PurgeSoftDeleted() != 1 {
+		t.Error("PurgeSoftDeleted should remove items within the window")
+	}
+}
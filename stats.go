@@ -0,0 +1,159 @@
+package syncmap
+
+import "sync/atomic"
+
+// Stats holds the operation counters of a map, see WithStats.
+type Stats struct {
+	Hits        uint64 // Gets finding the key
+	Misses      uint64 // Gets not finding the key
+	Sets        uint64 // values stored, by any write
+	Deletes     uint64 // calls to Delete
+	Evictions   uint64 // items evicted by the policy of a bounded map
+	Expirations uint64 // expired items removed
+}
+
+// Returns the fraction of Gets that found the key, 0 without any Get
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// The counters of a shard, updated atomically
+type shardStats struct {
+	hits, misses, sets, deletes, evictions, expirations atomic.Uint64
+}
+
+// WithStats makes the map count hits, misses, sets, deletes and expirations,
+// as reported by Stats, including those of the Ctx variants. Counters are
+// kept per shard so counting adds no contention between shards. Evictions
+// are always counted. Returns the map for chaining.
+func (m *Map[K, V]) WithStats() *Map[K, V] {
+	m.statsOn.Store(true)
+	return m
+}
+
+// Returns the sum of the counters of all shards. Counters start when
+// WithStats is called and are read one at a time, so they may be slightly
+// inconsistent with each other under concurrent use.
+func (m *Map[K, V]) Stats() Stats {
+	var s Stats
+	for _, shard := range m.shards() {
+		s.add(shard.stats.load())
+	}
+	if r := m.retired.Load(); r != nil {
+		s.add(r.load())
+	}
+	return s
+}
+
+// retiredStats holds the counters of the shards replaced by Reshard.
+// Operations that located a shard before it was replaced may still count on
+// it, so the counters of the last replaced shards are kept and read along
+// with the current ones. Those replaced earlier are folded into a total.
+type retiredStats struct {
+	folded Stats
+	shards []*shardStats
+}
+
+// Returns the sum of the retired counters
+func (r *retiredStats) load() Stats {
+	s := r.folded
+	for _, shard := range r.shards {
+		s.add(shard.load())
+	}
+	return s
+}
+
+// Retires the counters of shards replaced by Reshard, folding those of the
+// shards it replaced before
+func (m *Map[K, V]) retireStats(shards []*shard[K, V]) {
+	r := &retiredStats{shards: make([]*shardStats, len(shards))}
+	if prev := m.retired.Load(); prev != nil {
+		r.folded = prev.load()
+	}
+	for i, shard := range shards {
+		r.shards[i] = &shard.stats
+	}
+	m.retired.Store(r)
+}
+
+// Returns the counters of a shard
+func (s *shardStats) load() Stats {
+	return Stats{
+		Hits:        s.hits.Load(),
+		Misses:      s.misses.Load(),
+		Sets:        s.sets.Load(),
+		Deletes:     s.deletes.Load(),
+		Evictions:   s.evictions.Load(),
+		Expirations: s.expirations.Load(),
+	}
+}
+
+// Adds the counters of o
+func (s *Stats) add(o Stats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Sets += o.Sets
+	s.Deletes += o.Deletes
+	s.Evictions += o.Evictions
+	s.Expirations += o.Expirations
+}
+
+// Counts a Get of the shard if stats are enabled
+func (m *Map[K, V]) countGet(shard *shard[K, V], hit bool) {
+	if !m.statsOn.Load() {
+		return
+	}
+	if hit {
+		shard.stats.hits.Add(1)
+	} else {
+		shard.stats.misses.Add(1)
+	}
+}
+
+// Counts expired items removed from the shard if stats are enabled
+func (m *Map[K, V]) countExpired(shard *shard[K, V], n int) {
+	if n > 0 && m.statsOn.Load() {
+		shard.stats.expirations.Add(uint64(n))
+	}
+}
+
+// ShardStat describes a shard of a map, see ShardStats.
+type ShardStat struct {
+	Entries int   // number of items, including expired ones not yet removed
+	Cost    int64 // total cost of the items of a cost-bounded map, else 0
+	Stats   Stats // operation counters of the shard
+}
+
+// ShardStats returns the stats of each shard, in shard order, to detect hash
+// skew concentrating keys or operations in a few shards. Each shard is read
+// under its read lock in turn. The counters of shards replaced by Reshard
+// are only part of Stats.
+func (m *Map[K, V]) ShardStats() []ShardStat {
+	shards := m.shards()
+	stats := make([]ShardStat, len(shards))
+	for i, shard := range shards {
+		shard.RLock()
+		stats[i] = ShardStat{Entries: len(shard.items), Cost: shard.cost}
+		shard.RUnlock()
+		stats[i].Stats = shard.stats.load()
+	}
+	return stats
+}
+
+// Skew returns the ratio of the entries of the fullest shard to the mean
+// number of entries per shard: 1 for a perfectly even distribution, and the
+// shard count when all entries are in one shard. Returns 0 without entries.
+func Skew(stats []ShardStat) float64 {
+	total, most := 0, 0
+	for _, s := range stats {
+		total += s.Entries
+		most = max(most, s.Entries)
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(most) * float64(len(stats)) / float64(total)
+}
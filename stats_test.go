@@ -0,0 +1,105 @@
+package syncmap
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Stats(t *testing.T) {
+	m := New64().WithStats()
+	m.Set(1, 1)
+	m.Set(2, 2)
+	m.Get(1)
+	m.Get(1)
+	m.Get(3)
+	m.Delete(2)
+	m.SetWithTTL(4, 4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	m.DeleteExpired()
+
+	s := m.Stats()
+	want := Stats{Hits: 2, Misses: 1, Sets: 3, Deletes: 1, Expirations: 1}
+	if s != want {
+		t.Errorf("stats = %+v, want %+v", s, want)
+	}
+
+	// Every write storing a value counts as a set.
+	m.Update(1, func(interface{}, bool) (interface{}, bool) { return 10, true })
+	m.Upsert(5, 5, func(existing, incoming interface{}) interface{} { return incoming })
+	m.CompareAndSwap(5, 5, 6)
+	m.CompareAndSwap(5, 5, 7)
+	m.SetWithCost(6, 6, 1)
+	if sets := m.Stats().Sets; sets != 3+4 {
+		t.Error("stores should be counted as sets", sets)
+	}
+	if r := s.HitRatio(); r < 0.66 || r > 0.67 {
+		t.Error("hit ratio should be hits over gets", r)
+	}
+
+	if s := New64().Stats(); s != (Stats{}) || s.HitRatio() != 0 {
+		t.Error("stats of a new map should be zero", s)
+	}
+}
+
+func Test_StatsEvictions(t *testing.T) {
+	m := NewWithCapacity64(8)
+	for i := uint64(0); i < 100; i++ {
+		m.Set(i, i)
+	}
+	if s := m.Stats(); s.Evictions != uint64(100-m.Size()) {
+		t.Error("evictions should be counted without stats enabled", s.Evictions, m.Size())
+	}
+}
+
+// Number of runs of Test_PublishExpvar, naming a new variable in each run
+var expvarRuns atomic.Int32
+
+func Test_PublishExpvar(t *testing.T) {
+	m := New64().WithStats()
+	m.Set(1, 1)
+	m.Get(1)
+	name := fmt.Sprint("syncmap_test_expvar_", expvarRuns.Add(1))
+	m.PublishExpvar(name)
+	var vars struct {
+		Size     int     `json:"size"`
+		Hits     uint64  `json:"hits"`
+		HitRatio float64 `json:"hit_ratio"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Size != 1 || vars.Hits != 1 || vars.HitRatio != 1 {
+		t.Error("expvar should publish the size and stats", vars)
+	}
+}
+
+func Test_ShardStats(t *testing.T) {
+	m := NewWithShard64(4).WithStats()
+	for i := uint64(0); i < 1000; i++ {
+		m.Set(i, i)
+	}
+	stats := m.ShardStats()
+	entries, sets := 0, uint64(0)
+	for _, s := range stats {
+		entries += s.Entries
+		sets += s.Stats.Sets
+	}
+	if len(stats) != 4 || entries != 1000 || sets != 1000 {
+		t.Error("shard stats should cover every shard", stats)
+	}
+	if skew := Skew(stats); skew < 1 || skew > 1.2 {
+		t.Error("default hashing should spread keys evenly", skew)
+	}
+
+	skewed := []ShardStat{{Entries: 10}, {}, {}, {}}
+	if Skew(skewed) != 4 {
+		t.Error("skew of a single full shard should be the shard count", Skew(skewed))
+	}
+	if Skew(nil) != 0 {
+		t.Error("skew without entries should be 0")
+	}
+}
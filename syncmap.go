@@ -3,178 +3,75 @@ package syncmap
 
 import (
 	"fmt"
-	"math/rand"
-	"sync"
-	"time"
+	"math/bits"
+	"runtime"
 )
 
 const (
-	defaultShardCount uint8 = 32
+	defaultShardCount uint32 = 32
 )
 
-// syncMap wraps built-in map by using RWMutex for concurrent safe.
-type syncMap struct {
-	items map[uint32]interface{}
-	sync.RWMutex
-}
+// SyncMap is a thread safe map with uint32 keys.
+type SyncMap = Map[uint32, interface{}]
 
-// SyncMap keeps a slice of *syncMap with length of `shardCount`.
-// Using a slice of syncMap instead of a large one is to avoid lock bottlenecks.
-type SyncMap struct {
-	shardCount uint8
-	shards     []*syncMap
-}
+// Item is a pair of key and value of a SyncMap
+type Item = Entry[uint32, interface{}]
 
 // Create a new SyncMap with default shard count.
 func New() *SyncMap {
 	return NewWithShard(defaultShardCount)
 }
 
-// Create a new SyncMap with given shard count.
-// NOTE: shard count must be power of 2, default shard count will be used otherwise.
-func NewWithShard(shardCount uint8) *SyncMap {
-	if !isPowerOfTwo(shardCount) {
-		shardCount = defaultShardCount
-	}
-	m := new(SyncMap)
-	m.shardCount = shardCount
-	m.shards = make([]*syncMap, m.shardCount)
-	for i, _ := range m.shards {
-		m.shards[i] = &syncMap{items: make(map[uint32]interface{})}
-	}
-	return m
-}
-
-// Find the specific shard with the given key
-func (m *SyncMap) locate(key uint32) *syncMap {
-	strkey := fmt.Sprintf("%d", key)
-	return m.shards[bkdrHash(strkey)&uint32((m.shardCount-1))]
+// Create a new SyncMap holding at most about capacity items, evicting the least
+// recently used ones. See NewMapWithCapacity.
+func NewWithCapacity(capacity int) *SyncMap {
+	return NewMapWithCapacity[uint32, interface{}](capacity)
 }
 
-// Retrieves a value
-func (m *SyncMap) Get(key uint32) (value interface{}, ok bool) {
-	shard := m.locate(key)
-	shard.RLock()
-	value, ok = shard.items[key]
-	shard.RUnlock()
-	return
+// Create a new SyncMap with a shard count suited to the machine. See NewMapAuto.
+func NewAuto() *SyncMap {
+	return NewMapAuto[uint32, interface{}]()
 }
 
-// Sets value with the given key
-func (m *SyncMap) Set(key uint32, value interface{}) {
-	shard := m.locate(key)
-	shard.Lock()
-	shard.items[key] = value
-	shard.Unlock()
+// Create a new SyncMap with default shard count holding a copy of items. See NewMapFrom.
+func FromMap(items map[uint32]interface{}) *SyncMap {
+	return NewMapFrom(items)
 }
 
-// Removes an item
-func (m *SyncMap) Delete(key uint32) {
-	shard := m.locate(key)
-	shard.Lock()
-	delete(shard.items, key)
-	shard.Unlock()
-}
-
-// Pop delete and return a random item in the cache
-func (m *SyncMap) Pop() (uint32, interface{}) {
-	if m.Size() == 0 {
-		panic("syncmap: map is empty")
-	}
-
-	var (
-		key   uint32
-		value interface{}
-		found = false
-		n     = int(m.shardCount)
-	)
-
-	for !found {
-		idx := rand.Intn(n)
-		shard := m.shards[idx]
-		shard.Lock()
-		if len(shard.items) > 0 {
-			found = true
-			for key, value = range shard.items {
-				break
-			}
-			delete(shard.items, key)
-		}
-		shard.Unlock()
-	}
-
-	return key, value
-}
-
-// Whether SyncMap has the given key
-func (m *SyncMap) Has(key uint32) bool {
-	_, ok := m.Get(key)
-	return ok
-}
-
-// Returns the number of items
-func (m *SyncMap) Size() int {
-	size := 0
-	for _, shard := range m.shards {
-		shard.RLock()
-		size += len(shard.items)
-		shard.RUnlock()
-	}
-	return size
+// Create a new SyncMap with given shard count.
+// NOTE: shard count must be power of 2, default shard count will be used otherwise.
+func NewWithShard(shardCount uint32) *SyncMap {
+	return NewMapWithShard[uint32, interface{}](shardCount)
 }
 
-// Wipes all items from the map
-func (m *SyncMap) Flush() int {
-	size := 0
-	for _, shard := range m.shards {
-		shard.Lock()
-		size += len(shard.items)
-		shard.items = make(map[uint32]interface{})
-		shard.Unlock()
-	}
-	return size
+// HashSplitMix is the hasher used for shard selection of integer keys: the
+// splitmix64 finalizer of the key, folded to 32 bits. It is allocation free
+// and spreads sequential keys evenly across shards.
+func HashSplitMix(key uint64) uint32 {
+	key ^= key >> 30
+	key *= 0xbf58476d1ce4e5b9
+	key ^= key >> 27
+	key *= 0x94d049bb133111eb
+	key ^= key >> 31
+	return uint32(key >> 32)
 }
 
-// Returns a channel from which each key in the map can be read
-func (m *SyncMap) IterKeys() <-chan uint32 {
-	ch := make(chan uint32)
-	go func() {
-		for _, shard := range m.shards {
-			shard.RLock()
-			for key, _ := range shard.items {
-				ch <- key
-			}
-			shard.RUnlock()
-		}
-		close(ch)
-	}()
-	return ch
-}
+const seed uint32 = 131 // 31 131 1313 13131 131313 etc..
 
-// Item is a pair of key and value
-type Item struct {
-	Key   uint32
-	Value interface{}
+// HashBKDR is the BKDR hash of the key's decimal representation, which was
+// used for shard selection of integer keys before HashSplitMix. It is kept
+// so that tools can compare how a population of keys is distributed.
+func HashBKDR(key uint64) uint32 {
+	return bkdrHash(fmt.Sprintf("%d", key))
 }
 
-// Return a channel from which each item (key:value pair) in the map can be read
-func (m *SyncMap) IterItems() <-chan Item {
-	ch := make(chan Item)
-	go func() {
-		for _, shard := range m.shards {
-			shard.RLock()
-			for key, value := range shard.items {
-				ch <- Item{key, value}
-			}
-			shard.RUnlock()
-		}
-		close(ch)
-	}()
-	return ch
+// HashBKDRString is the BKDR hash of a string, which was used for shard
+// selection of string keys before hash/maphash. Pass it to NewMapWithHasher
+// to keep the shard placement of string keys of earlier versions.
+func HashBKDRString(key string) uint32 {
+	return bkdrHash(key)
 }
 
-const seed uint32 = 131 // 31 131 1313 13131 131313 etc..
-
 func bkdrHash(str string) uint32 {
 	var h uint32
 
@@ -185,10 +82,16 @@ func bkdrHash(str string) uint32 {
 	return h
 }
 
-func isPowerOfTwo(x uint8) bool {
-	return x != 0 && (x&(x-1) == 0)
+// Returns a shard count for the current GOMAXPROCS: four shards per
+// processor rounded up to a power of two, and at least the default
+func autoShardCount() uint32 {
+	n := uint32(4 * runtime.GOMAXPROCS(0))
+	if n <= defaultShardCount {
+		return defaultShardCount
+	}
+	return 1 << bits.Len32(n-1)
 }
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
+func isPowerOfTwo(x uint32) bool {
+	return x != 0 && (x&(x-1) == 0)
 }
@@ -1,7 +1,9 @@
 package syncmap
 
 import (
+	"errors"
 	"testing"
+	"time"
 )
 
 func Test_New64(t *testing.T) {
@@ -9,19 +11,19 @@ func Test_New64(t *testing.T) {
 	if m1 == nil {
 		t.Error("New64(): map is nil")
 	}
-	if m1.shardCount != defaultShardCount {
+	if m1.shardCount() != defaultShardCount {
 		t.Error("New(): map's shard count is wrong")
 	}
 	if m1.Size() != 0 {
 		t.Error("New(): new map should be empty")
 	}
 
-	var shardCount uint8 = 64
+	var shardCount uint32 = 64
 	m2 := NewWithShard64(shardCount)
 	if m2 == nil {
 		t.Error("NewWithShard64(): map is nil")
 	}
-	if m2.shardCount != shardCount {
+	if m2.shardCount() != shardCount {
 		t.Error("NewWithShard64(): map's shard count is wrong")
 	}
 	if m2.Size() != 0 {
@@ -91,7 +93,7 @@ func Test_Size64(t *testing.T) {
 }
 
 func Test_Flush64(t *testing.T) {
-	var shardCount uint8 = 64
+	var shardCount uint32 = 64
 	m := NewWithShard64(shardCount)
 	for i := 0; i < 42; i++ {
 		m.Set(uint64(i), i)
@@ -103,7 +105,7 @@ func Test_Flush64(t *testing.T) {
 	if m.Size() != 0 {
 		t.Error("Flush should remove all items from map", m.Size())
 	}
-	if m.shardCount != shardCount {
+	if m.shardCount() != shardCount {
 		t.Error("map should have the same shardCount after Flush")
 	}
 }
@@ -146,3 +148,621 @@ func Test_Pop64(t *testing.T) {
 		t.Error("Size should be 0 after pop the only item")
 	}
 }
+
+func Test_PopSizeExpired64(t *testing.T) {
+	m := New64()
+	m.SetWithTTL(1, "x", time.Millisecond)
+	time.Sleep(3 * time.Millisecond)
+	if m.Size() != 0 {
+		t.Error("Size should not count expired items", m.Size())
+	}
+	if n := m.PopInto(make([]Item64, 4)); n != 0 {
+		t.Error("PopInto should skip expired items", n)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Pop should panic when only expired items are left")
+			}
+		}()
+		m.Pop()
+	}()
+
+	m.Set(2, "y")
+	if k, v := m.Pop(); k != 2 || v != "y" {
+		t.Error("Pop should return the live item", k, v)
+	}
+}
+
+func Test_ExpireMany64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 10; i++ {
+		m.Set(uint64(i), i)
+	}
+
+	count := m.ExpireMany([]uint64{0, 1, 2, 3, 100}, time.Millisecond)
+	if count != 4 {
+		t.Error("ExpireMany should return the number of existing keys", count)
+	}
+	if m.ExpireMany([]uint64{3}, 0) != 1 {
+		t.Error("ExpireMany should clear the expiration of an existing key")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if m.Has(uint64(i)) {
+			t.Error("key should be expired", i)
+		}
+	}
+	if !m.Has(3) || !m.Has(4) {
+		t.Error("keys without expiration should be kept")
+	}
+	if m.ExpireMany([]uint64{0}, time.Minute) != 0 {
+		t.Error("ExpireMany should not revive an expired key")
+	}
+
+	m.Set(5, 5)
+	m.ExpireMany([]uint64{5}, time.Millisecond)
+	m.Set(5, 6)
+	time.Sleep(5 * time.Millisecond)
+	if !m.Has(5) {
+		t.Error("Set should clear the expiration")
+	}
+}
+
+func Test_PopInto64(t *testing.T) {
+	m := New64()
+	buf := make([]Item64, 0, 8)
+	if m.PopInto(buf) != 0 {
+		t.Error("PopInto should remove nothing from an empty map")
+	}
+
+	for i := 0; i < 10; i++ {
+		m.Set(uint64(i), i)
+	}
+
+	n := m.PopInto(buf)
+	if n != 8 {
+		t.Error("PopInto should fill the buffer up to its capacity", n)
+	}
+	for _, item := range buf[:n] {
+		if item.Value.(int) != int(item.Key) || m.Has(item.Key) {
+			t.Error("PopInto should remove and return the items", item)
+		}
+	}
+
+	if m.PopInto(buf) != 2 {
+		t.Error("PopInto should return the remaining items")
+	}
+	if m.Size() != 0 {
+		t.Error("Size should be 0 after draining the map")
+	}
+}
+
+func Test_RefreshIfStale64(t *testing.T) {
+	m := New64()
+	valid := func(v interface{}) bool { return v.(int) == 1 }
+
+	if m.RefreshIfStale(1, 0, valid) {
+		t.Error("RefreshIfStale should return false for missing key")
+	}
+
+	m.Set(1, 1)
+	m.Set(2, 2)
+	m.ExpireMany([]uint64{1, 2}, 20*time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	if !m.RefreshIfStale(1, time.Millisecond, valid) {
+		t.Error("RefreshIfStale should succeed when validation passes")
+	}
+	if m.RefreshIfStale(2, time.Millisecond, valid) {
+		t.Error("RefreshIfStale should fail when validation fails")
+	}
+	if !m.RefreshIfStale(2, time.Hour, valid) {
+		t.Error("RefreshIfStale should not validate a fresh entry")
+	}
+
+	time.Sleep(15 * time.Millisecond)
+	if !m.Has(1) {
+		t.Error("refreshed entry should have its TTL extended")
+	}
+	if m.Has(2) {
+		t.Error("entry failing validation should expire")
+	}
+}
+
+func Test_Locate64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 64*int(m.shardCount()); i++ {
+		m.Set(uint64(i), i)
+	}
+	for i, shard := range m.shards() {
+		if len(shard.items) == 0 {
+			t.Error("sequential keys should be spread over every shard", i)
+		}
+	}
+
+	if n := testing.AllocsPerRun(100, func() { m.locate(7788414) }); n != 0 {
+		t.Error("locating a key should not allocate", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { m.Get(7788414) }); n != 0 {
+		t.Error("Get should not allocate", n)
+	}
+}
+
+func Test_ManyShards64(t *testing.T) {
+	for _, shardCount := range []uint32{256, 1024} {
+		m := NewWithShard64(shardCount)
+		if len(m.shards()) != int(shardCount) {
+			t.Error("shard counts above 255 should be supported", len(m.shards()))
+		}
+		for i := uint64(0); i < 64*uint64(shardCount); i++ {
+			m.Set(i, i)
+		}
+		for i, size := range m.ShardSizes() {
+			if size == 0 {
+				t.Error("every shard should be used", shardCount, i)
+				break
+			}
+		}
+	}
+	if len(NewWithShard64(1000).shards()) != int(defaultShardCount) {
+		t.Error("shard counts that are not a power of 2 should use the default")
+	}
+}
+
+func Test_NewWithHasher64(t *testing.T) {
+	// Co-locate the keys of a tenant, held in the high 32 bits.
+	m := NewWithHasher(16, func(key uint64) uint32 { return uint32(key >> 32) })
+	for tenant := uint64(0); tenant < 16; tenant++ {
+		shard := m.locate(tenant << 32)
+		for i := uint64(1); i < 100; i++ {
+			if m.locate(tenant<<32|i) != shard {
+				t.Fatal("keys of a tenant should share a shard", tenant, i)
+			}
+		}
+	}
+	if m.locate(1<<32) == m.locate(2<<32) {
+		t.Error("tenants should be spread across shards")
+	}
+}
+
+func Test_SetMany64(t *testing.T) {
+	m := NewWithShard64(4).WithStats()
+	items := make([]Item64, 0, 1001)
+	for i := 0; i < 1000; i++ {
+		items = append(items, Item64{Key: uint64(i), Value: i})
+	}
+	items = append(items, Item64{Key: 7, Value: "last"})
+	m.SetMany(items)
+	if m.Size() != 1000 {
+		t.Error("SetMany should set every item", m.Size())
+	}
+	if v, _ := m.Get(7); v != "last" {
+		t.Error("a later item should overwrite an earlier one with the same key", v)
+	}
+	if s := m.Stats(); s.Sets != 1001 {
+		t.Error("SetMany should count a set per item", s.Sets)
+	}
+}
+
+func Test_GetMany64(t *testing.T) {
+	m := NewWithShard64(4).WithStats()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+	m.SetWithTTL(100, 100, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	keys := []uint64{1, 2, 3, 50, 100, 200}
+	found := m.GetMany(keys)
+	if len(found) != 4 || found[50].(int) != 50 {
+		t.Error("GetMany should return the keys that were found", found)
+	}
+	if _, ok := found[100]; ok {
+		t.Error("GetMany should not return expired keys")
+	}
+	if s := m.Stats(); s.Hits != 4 || s.Misses != 2 {
+		t.Error("GetMany should count a hit or miss per key", s)
+	}
+
+	m.WithLockFreeReads()
+	if found := m.GetMany(keys); len(found) != 4 {
+		t.Error("GetMany should read the published items", found)
+	}
+}
+
+func Test_GetOrSet64(t *testing.T) {
+	m := New64()
+	actual, loaded := m.GetOrSet(1, 1)
+	if loaded || actual.(int) != 1 {
+		t.Error("GetOrSet should set a missing key")
+	}
+	actual, loaded = m.GetOrSet(1, 2)
+	if !loaded || actual.(int) != 1 {
+		t.Error("GetOrSet should return the existing value")
+	}
+
+	m.ExpireMany([]uint64{1}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	actual, loaded = m.GetOrSet(1, 3)
+	if loaded || actual.(int) != 3 {
+		t.Error("GetOrSet should replace an expired value")
+	}
+}
+
+func Test_CompareAndSwap64(t *testing.T) {
+	m := New64()
+	if m.CompareAndSwap(1, nil, 1) {
+		t.Error("CompareAndSwap should fail for missing key")
+	}
+	m.Set(1, 1)
+	if m.CompareAndSwap(1, 2, 3) {
+		t.Error("CompareAndSwap should fail when the value differs")
+	}
+	if !m.CompareAndSwap(1, 1, 3) {
+		t.Error("CompareAndSwap should succeed when the value is equal")
+	}
+	if v, _ := m.Get(1); v.(int) != 3 {
+		t.Error("CompareAndSwap should set the new value")
+	}
+}
+
+func Test_CompareAndDelete64(t *testing.T) {
+	m := New64()
+	if m.CompareAndDelete(1, nil) {
+		t.Error("CompareAndDelete should fail for missing key")
+	}
+	m.Set(1, 1)
+	if m.CompareAndDelete(1, 2) || !m.Has(1) {
+		t.Error("CompareAndDelete should keep the key when the value differs")
+	}
+	if !m.CompareAndDelete(1, 1) || m.Has(1) {
+		t.Error("CompareAndDelete should remove the key when the value is equal")
+	}
+}
+
+func Test_Swap64(t *testing.T) {
+	m := New64()
+	if v, loaded := m.Swap(1, "a"); loaded || v != nil {
+		t.Error("Swap should not load a missing key", v)
+	}
+	if v, loaded := m.Swap(1, "b"); !loaded || v != "a" {
+		t.Error("Swap should return the previous value", v)
+	}
+	if v, _ := m.Get(1); v != "b" {
+		t.Error("Swap should store the new value", v)
+	}
+}
+
+func Test_DeleteMany64(t *testing.T) {
+	m := NewWithShard64(4)
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+	m.SetWithTTL(100, 100, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if n := m.DeleteMany([]uint64{1, 2, 2, 50, 100, 200}); n != 3 {
+		t.Error("DeleteMany should count the keys that were present", n)
+	}
+	if m.Size() != 97 || m.Has(2) || m.Has(50) {
+		t.Error("DeleteMany should remove the keys", m.Size())
+	}
+}
+
+func Test_GetAndDelete64(t *testing.T) {
+	m := New64()
+	if _, ok := m.GetAndDelete(1); ok {
+		t.Error("GetAndDelete should fail for missing key")
+	}
+	m.Set(1, 1)
+	if v, ok := m.GetAndDelete(1); !ok || v.(int) != 1 {
+		t.Error("GetAndDelete should return the value", v)
+	}
+	if m.Has(1) {
+		t.Error("GetAndDelete should remove the key")
+	}
+}
+
+func Test_Lookup64(t *testing.T) {
+	m := New64()
+	m.Set(1, 1)
+	if v, err := m.Lookup(1); err != nil || v.(int) != 1 {
+		t.Error("Lookup should return a present value", v, err)
+	}
+	if _, err := m.Lookup(2); !errors.Is(err, ErrNotFound) {
+		t.Error("Lookup should return ErrNotFound for a missing key", err)
+	}
+}
+
+func Test_Update64(t *testing.T) {
+	m := New64()
+	appendValue := func(v int) func(interface{}, bool) (interface{}, bool) {
+		return func(old interface{}, exists bool) (interface{}, bool) {
+			if !exists {
+				return []int{v}, true
+			}
+			return append(old.([]int), v), true
+		}
+	}
+	m.Update(1, appendValue(1))
+	m.Update(1, appendValue(2))
+	if v, _ := m.Get(1); len(v.([]int)) != 2 {
+		t.Error("Update should modify the existing value", v)
+	}
+
+	m.ExpireMany([]uint64{1}, time.Hour)
+	m.Update(1, appendValue(3))
+	if _, ok := m.shards()[m.shardIndex(1)].expires[1]; !ok {
+		t.Error("Update should keep the expiration")
+	}
+
+	m.Update(1, func(interface{}, bool) (interface{}, bool) { return nil, false })
+	if m.Has(1) {
+		t.Error("Update should remove the key when keep is false")
+	}
+
+	m.WithTTL(time.Hour)
+	m.Update(2, appendValue(1))
+	if ttl, ok := m.TTL(2); !ok || ttl < 59*time.Minute {
+		t.Error("Update should apply the default TTL to a new key", ttl, ok)
+	}
+}
+
+func Test_Upsert64(t *testing.T) {
+	m := New64()
+	sum := func(existing, incoming interface{}) interface{} {
+		return existing.(int) + incoming.(int)
+	}
+	if v := m.Upsert(1, 2, sum); v.(int) != 2 {
+		t.Error("Upsert should set a missing key", v)
+	}
+	if v := m.Upsert(1, 3, sum); v.(int) != 5 {
+		t.Error("Upsert should merge into the existing value", v)
+	}
+	if v, _ := m.Get(1); v.(int) != 5 {
+		t.Error("Upsert should store the merged value", v)
+	}
+}
+
+func Test_SetIfAbsentPresent64(t *testing.T) {
+	m := New64()
+	if m.SetIfPresent(1, 1) || m.Has(1) {
+		t.Error("SetIfPresent should not create a missing key")
+	}
+	if !m.SetIfAbsent(1, 1) {
+		t.Error("SetIfAbsent should create a missing key")
+	}
+	if m.SetIfAbsent(1, 2) {
+		t.Error("SetIfAbsent should not overwrite an existing key")
+	}
+	if !m.SetIfPresent(1, 3) {
+		t.Error("SetIfPresent should overwrite an existing key")
+	}
+	if v, _ := m.Get(1); v.(int) != 3 {
+		t.Error("value should be the one set by SetIfPresent", v)
+	}
+}
+
+func Test_PopWeighted64(t *testing.T) {
+	m := New64()
+	weight := func(key uint64, value interface{}) float64 { return float64(value.(int)) }
+	if _, _, ok := m.PopWeighted(weight); ok {
+		t.Error("PopWeighted should fail on an empty map")
+	}
+	m.Set(1, 0)
+	if _, _, ok := m.PopWeighted(weight); ok {
+		t.Error("PopWeighted should never choose an entry without weight")
+	}
+
+	heavy := 0
+	for round := 0; round < 200; round++ {
+		m.Set(2, 1)
+		m.Set(3, 9)
+		key, _, ok := m.PopWeighted(weight)
+		if !ok || m.Has(key) {
+			t.Fatal("PopWeighted should remove the chosen entry", key)
+		}
+		if key == 3 {
+			heavy++
+		}
+		m.Delete(2)
+		m.Delete(3)
+	}
+	if heavy < 150 {
+		t.Error("heavier entries should be chosen proportionally more often", heavy)
+	}
+}
+
+func Test_Range64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+	m.ExpireMany([]uint64{0}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	seen := make(map[uint64]bool)
+	m.Range(func(key uint64, value interface{}) bool {
+		if value.(int) != int(key) {
+			t.Error("Range should pass the value of each key", key)
+		}
+		seen[key] = true
+		m.Delete(key)
+		return true
+	})
+	if len(seen) != 99 || seen[0] {
+		t.Error("Range should visit every item that is not expired", len(seen))
+	}
+	if m.Size() != 0 {
+		t.Error("fn should be able to call methods of the map", m.Size())
+	}
+
+	m.Set(1, 1)
+	m.Set(2, 2)
+	n := 0
+	m.Range(func(uint64, interface{}) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Error("Range should stop when fn returns false", n)
+	}
+}
+
+func Test_KeysValuesSlice64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), int(i))
+	}
+	m.ExpireMany([]uint64{0}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	keys := m.KeysSlice()
+	values := m.ValuesSlice()
+	if len(keys) != 99 || len(values) != 99 {
+		t.Error("slices should hold every item that is not expired", len(keys), len(values))
+	}
+	keySum, valueSum := uint64(0), 0
+	for i := range keys {
+		keySum += keys[i]
+		valueSum += values[i].(int)
+	}
+	if keySum != 4950 || valueSum != 4950 {
+		t.Error("slices should hold every key and value", keySum, valueSum)
+	}
+}
+
+func Test_Snapshot64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+	snapshot := m.Snapshot()
+	m.Set(100, 100)
+	m.Delete(0)
+	if len(snapshot) != 100 || snapshot[0].(int) != 0 {
+		t.Error("snapshot should not change with the map", len(snapshot))
+	}
+}
+
+func Test_FromMapToMap64(t *testing.T) {
+	items := make(map[uint64]interface{})
+	for i := 0; i < 1000; i++ {
+		items[uint64(i)] = i
+	}
+	m := FromMap64(items)
+	items[1000] = 1000
+	if m.Size() != 1000 {
+		t.Error("FromMap64 should copy the items", m.Size())
+	}
+	if v, ok := m.Get(7); !ok || v.(int) != 7 {
+		t.Error("FromMap64 should place the items in their shards", v, ok)
+	}
+
+	plain := m.ToMap()
+	if len(plain) != 1000 || plain[999].(int) != 999 {
+		t.Error("ToMap should return every item", len(plain))
+	}
+}
+
+func Test_FlushFunc64(t *testing.T) {
+	m := New64()
+	for i := 0; i < 100; i++ {
+		m.Set(uint64(i), i)
+	}
+	m.ExpireMany([]uint64{0}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	flushed := 0
+	count := m.FlushFunc(func(key uint64, value interface{}) {
+		// fn runs without any lock held, after the item was removed.
+		if m.Has(key) {
+			t.Error("item should be removed before fn is called", key)
+		}
+		flushed++
+	})
+	if count != 100 || flushed != 99 {
+		t.Error("FlushFunc should pass the items that were not expired", count, flushed)
+	}
+	if m.Size() != 0 {
+		t.Error("FlushFunc should remove all items", m.Size())
+	}
+}
+
+func Test_IterItemsWrite64(t *testing.T) {
+	m := NewWithShard64(1)
+	for i := 0; i < 10; i++ {
+		m.Set(uint64(i), i)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for item := range m.IterItems() {
+			m.Set(item.Key, item.Value.(int)+1)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writing while iterating should not deadlock")
+	}
+	if v, _ := m.Get(0); v.(int) != 1 {
+		t.Error("writes made while iterating should be applied", v)
+	}
+}
+
+func Test_IterWhere64(t *testing.T) {
+	m := New64()
+	for i := uint64(0); i < 100; i++ {
+		m.Set(i, int(i))
+	}
+	n := 0
+	for item := range m.IterWhere(func(key uint64, value interface{}) bool { return value.(int)%10 == 0 }) {
+		if item.Key%10 != 0 {
+			t.Error("IterWhere should only yield matching items", item)
+		}
+		n++
+	}
+	if n != 10 {
+		t.Error("IterWhere should yield every matching item", n)
+	}
+}
+
+func Test_ShardSizes64(t *testing.T) {
+	m := NewWithShard64(4)
+	for i := uint64(0); i < 100; i++ {
+		m.Set(i, i)
+	}
+	sizes := m.ShardSizes()
+	total := 0
+	for _, size := range sizes {
+		total += size
+	}
+	if len(sizes) != 4 || total != 100 {
+		t.Error("shard sizes should cover every shard and item", sizes)
+	}
+}
+
+func Test_ShrinkToFit64(t *testing.T) {
+	m := New64()
+	for i := uint64(0); i < 10000; i++ {
+		m.SetWithTTL(i, i, time.Hour)
+	}
+	fork := m.Fork()
+	for i := uint64(0); i < 9990; i++ {
+		m.Delete(i)
+	}
+	m.ShrinkToFit()
+	if m.Size() != 10 {
+		t.Error("ShrinkToFit should keep the items", m.Size())
+	}
+	if ttl, ok := m.TTL(9995); !ok || ttl < 59*time.Minute {
+		t.Error("ShrinkToFit should keep expirations", ttl, ok)
+	}
+	m.Set(1, 1)
+	if fork.Size() != 10000 || m.Size() != 11 {
+		t.Error("ShrinkToFit should not affect forks", fork.Size(), m.Size())
+	}
+}
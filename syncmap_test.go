@@ -1,6 +1,7 @@
 package syncmap
 
 import (
+	"runtime"
 	"testing"
 )
 
@@ -9,19 +10,19 @@ func Test_New(t *testing.T) {
 	if m1 == nil {
 		t.Error("New(): map is nil")
 	}
-	if m1.shardCount != defaultShardCount {
+	if m1.shardCount() != defaultShardCount {
 		t.Error("New(): map's shard count is wrong")
 	}
 	if m1.Size() != 0 {
 		t.Error("New(): new map should be empty")
 	}
 
-	var shardCount uint8 = 64
+	var shardCount uint32 = 64
 	m2 := NewWithShard(shardCount)
 	if m2 == nil {
 		t.Error("NewWithShard(): map is nil")
 	}
-	if m2.shardCount != shardCount {
+	if m2.shardCount() != shardCount {
 		t.Error("NewWithShard(): map's shard count is wrong")
 	}
 	if m2.Size() != 0 {
@@ -91,7 +92,7 @@ func Test_Size(t *testing.T) {
 }
 
 func Test_Flush(t *testing.T) {
-	var shardCount uint8 = 64
+	var shardCount uint32 = 64
 	m := NewWithShard(shardCount)
 	for i := 0; i < 42; i++ {
 		m.Set(uint32(i), i)
@@ -103,7 +104,7 @@ func Test_Flush(t *testing.T) {
 	if m.Size() != 0 {
 		t.Error("Flush should remove all items from map", m.Size())
 	}
-	if m.shardCount != shardCount {
+	if m.shardCount() != shardCount {
 		t.Error("map should have the same shardCount after Flush")
 	}
 }
@@ -154,3 +155,30 @@ func Test_Pop(t *testing.T) {
 		t.Error("Size should be 0 after pop the only item")
 	}
 }
+
+func Test_CompareAndSwap(t *testing.T) {
+	m := New()
+	if m.CompareAndSwap(1, nil, 1) {
+		t.Error("CompareAndSwap should fail for missing key")
+	}
+	m.Set(1, "a")
+	if m.CompareAndSwap(1, "b", "c") {
+		t.Error("CompareAndSwap should fail when the value differs")
+	}
+	if !m.CompareAndSwap(1, "a", "c") {
+		t.Error("CompareAndSwap should succeed when the value is equal")
+	}
+	if v, _ := m.Get(1); v.(string) != "c" {
+		t.Error("CompareAndSwap should set the new value")
+	}
+}
+
+func Test_NewAuto(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for procs, want := range map[int]uint32{1: 32, 8: 32, 9: 64, 48: 256, 96: 512} {
+		runtime.GOMAXPROCS(procs)
+		if n := NewAuto().shardCount(); n != want {
+			t.Error("shard count should follow GOMAXPROCS", procs, n, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package syncmap
+
+// SyncMapString is a thread safe map with string keys, such as request IDs
+// or UUIDs. Shards are selected with hash/maphash, seeded per map.
+type SyncMapString = Map[string, interface{}]
+
+// ItemString is a pair of key and value of a SyncMapString
+type ItemString = Entry[string, interface{}]
+
+// Create a new SyncMapString with default shard count.
+func NewString() *SyncMapString {
+	return NewWithShardString(defaultShardCount)
+}
+
+// Create a new SyncMapString with a shard count suited to the machine. See
+// NewMapAuto.
+func NewAutoString() *SyncMapString {
+	return NewMapAuto[string, interface{}]()
+}
+
+// Create a new SyncMapString with default shard count holding a copy of items. See NewMapFrom.
+func FromMapString(items map[string]interface{}) *SyncMapString {
+	return NewMapFrom(items)
+}
+
+// Create a new SyncMapString holding at most about capacity items, evicting the least
+// recently used ones. See NewMapWithCapacity.
+func NewWithCapacityString(capacity int) *SyncMapString {
+	return NewMapWithCapacity[string, interface{}](capacity)
+}
+
+// Create a new SyncMapString with given shard count.
+// NOTE: shard count must be power of 2, default shard count will be used otherwise.
+func NewWithShardString(shardCount uint32) *SyncMapString {
+	return NewMapWithShard[string, interface{}](shardCount)
+}
@@ -0,0 +1,67 @@
+package syncmap
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_NewString(t *testing.T) {
+	m1 := NewString()
+	if m1 == nil {
+		t.Error("NewString(): map is nil")
+	}
+	if m1.shardCount() != defaultShardCount {
+		t.Error("NewString(): map's shard count is wrong")
+	}
+
+	var shardCount uint32 = 64
+	m2 := NewWithShardString(shardCount)
+	if m2.shardCount() != shardCount {
+		t.Error("NewWithShardString(): map's shard count is wrong")
+	}
+}
+
+func Test_SetGetString(t *testing.T) {
+	m := NewString()
+	m.Set("one", 1)
+	m.Set("two", 2)
+	if m.Size() != 2 {
+		t.Error("map should have 2 items.")
+	}
+
+	v, ok := m.Get("one")
+	if !ok || v.(int) != 1 {
+		t.Error("Get should return the value of an existing key")
+	}
+	if _, ok := m.Get("three"); ok {
+		t.Error("ok should be false when key is missing")
+	}
+
+	m.Delete("one")
+	if m.Has("one") {
+		t.Error("Delete should remove the given key from map")
+	}
+}
+
+func Test_LocateString(t *testing.T) {
+	m := NewString()
+	key := "3f2a9c1e-request-id"
+	other := NewString()
+	same := 0
+	for i := 0; i < 100; i++ {
+		k := key + strconv.Itoa(i)
+		if m.shardIndex(k) == other.shardIndex(k) {
+			same++
+		}
+	}
+	if same > 20 {
+		t.Error("maps should be seeded differently", same)
+	}
+	legacy := NewMapWithHasher[string, interface{}](32, HashBKDRString)
+	if legacy.locate(key) != legacy.shards()[bkdrHash(key)&31] {
+		t.Error("a map with HashBKDRString should locate keys with bkdrHash")
+	}
+	if n := testing.AllocsPerRun(100, func() { m.locate(key) }); n != 0 {
+		t.Error("locating a string key should not allocate", n)
+	}
+}
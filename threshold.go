@@ -0,0 +1,121 @@
+package syncmap
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Usage is how much of the budget of a bounded map is used.
+// Unbounded dimensions have a zero maximum.
+type Usage struct {
+	Entries    int64
+	MaxEntries int64
+	Cost       int64
+	MaxCost    int64
+}
+
+// Fraction of the budget used, from 0 to 1, the largest of the entry and
+// cost fractions
+func (u Usage) Fraction() float64 {
+	fraction := 0.0
+	if u.MaxEntries > 0 {
+		fraction = float64(u.Entries) / float64(u.MaxEntries)
+	}
+	if u.MaxCost > 0 {
+		if f := float64(u.Cost) / float64(u.MaxCost); f > fraction {
+			fraction = f
+		}
+	}
+	return fraction
+}
+
+// threshold is a callback fired when usage rises to a fraction of the budget.
+type threshold struct {
+	fraction float64
+	fn       func(Usage)
+	crossed  atomic.Bool
+}
+
+// budget tracks the usage of a bounded map across all of its shards.
+type budget struct {
+	entries    atomic.Int64
+	maxEntries int64
+	cost       atomic.Int64
+	maxCost    int64
+
+	mu         sync.Mutex // serializes OnThreshold
+	thresholds atomic.Pointer[[]*threshold]
+}
+
+func (b *budget) usage() Usage {
+	return Usage{
+		Entries:    b.entries.Load(),
+		MaxEntries: b.maxEntries,
+		Cost:       b.cost.Load(),
+		MaxCost:    b.maxCost,
+	}
+}
+
+func (b *budget) addEntries(delta int64) {
+	b.entries.Add(delta)
+	b.check()
+}
+
+// Adds to the cost, if the map has a budget
+func (b *budget) addCost(delta int64) {
+	if b != nil && delta != 0 {
+		b.cost.Add(delta)
+		b.check()
+	}
+}
+
+// Fires the thresholds the usage rose to and re-arms the ones it fell below
+func (b *budget) check() {
+	thresholds := b.thresholds.Load()
+	if thresholds == nil {
+		return
+	}
+	u := b.usage()
+	fraction := u.Fraction()
+	for _, t := range *thresholds {
+		if fraction >= t.fraction {
+			if t.crossed.CompareAndSwap(false, true) {
+				go t.fn(u)
+			}
+		} else {
+			t.crossed.Store(false)
+		}
+	}
+}
+
+// OnThreshold calls fn when the usage of the budget of the map rises to
+// fraction of it, such as 0.9, so applications can shed load or shorten
+// TTLs before the map starts evicting. fn runs in its own goroutine, once
+// per crossing: it fires again only after the usage has fallen below
+// fraction. If the usage is already above fraction, fn fires right away.
+// Panics if the map is not bounded, as by NewMapWithCapacity or
+// NewMapWithMaxCost.
+func (m *Map[K, V]) OnThreshold(fraction float64, fn func(Usage)) {
+	b := m.shards()[0].budget
+	if b == nil {
+		panic("syncmap: map has no budget")
+	}
+	b.mu.Lock()
+	var thresholds []*threshold
+	if p := b.thresholds.Load(); p != nil {
+		thresholds = append(thresholds, *p...)
+	}
+	thresholds = append(thresholds, &threshold{fraction: fraction, fn: fn})
+	b.thresholds.Store(&thresholds)
+	b.mu.Unlock()
+	b.check()
+}
+
+// Returns how much of the budget of the map is used, or the zero Usage if
+// the map is not bounded
+func (m *Map[K, V]) Usage() Usage {
+	if b := m.shards()[0].budget; b != nil {
+		return b.usage()
+	}
+	return Usage{}
+}
@@ -0,0 +1,50 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_OnThreshold(t *testing.T) {
+	m := NewWithCapacity64(64)
+	fired := make(chan Usage, 10)
+	m.OnThreshold(0.5, func(u Usage) { fired <- u })
+
+	for i := 0; i < 1000; i++ {
+		m.Set(uint64(i), i)
+	}
+	if u := m.Usage(); u.Entries != 64 || u.MaxEntries != 64 {
+		t.Error("usage should count the entries", u)
+	}
+	select {
+	case u := <-fired:
+		if u.Fraction() < 0.5 {
+			t.Error("threshold should fire once usage reaches it", u)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("threshold should fire")
+	}
+
+	m.Flush()
+	m.Set(1, 1)
+	select {
+	case u := <-fired:
+		t.Error("threshold should fire once per crossing", u)
+	case <-time.After(10 * time.Millisecond):
+	}
+	for i := 0; i < 1000; i++ {
+		m.Set(uint64(i), i)
+	}
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Error("threshold should fire again after usage fell below it")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("OnThreshold should panic for unbounded maps")
+		}
+	}()
+	New64().OnThreshold(0.5, func(Usage) {})
+}
@@ -0,0 +1,318 @@
+package syncmap
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// expiry records when a key expires and the TTL it was given.
+type expiry struct {
+	at  int64 // unix nanoseconds
+	ttl time.Duration
+}
+
+func newExpiry(ttl time.Duration) expiry {
+	return expiry{at: time.Now().Add(ttl).UnixNano(), ttl: ttl}
+}
+
+func (e expiry) expired(now int64) bool {
+	return now >= e.at
+}
+
+// Sets the TTL of a key, a non-positive ttl removes the expiration.
+// Must be called with the write lock held.
+func (shard *shard[K, V]) expire(key K, ttl time.Duration) {
+	if ttl <= 0 {
+		if shard.expires != nil {
+			shard.own()
+			shard.touch(key)
+			delete(shard.expires, key)
+		}
+		return
+	}
+	shard.setExpiry(key, newExpiry(ttl))
+}
+
+// Sets the expiration of a key, must be called with the write lock held
+func (shard *shard[K, V]) setExpiry(key K, e expiry) {
+	shard.own()
+	shard.touch(key)
+	if shard.expires == nil {
+		shard.expires = make(map[K]expiry)
+	}
+	shard.expires[key] = e
+}
+
+// SetWithTTL sets value with the given key, expiring after ttl. A
+// non-positive ttl sets the value without expiration, even with WithTTL. Expired
+// entries are invisible right away, and their memory is reclaimed by
+// DeleteExpired, or in the background by a janitor.
+func (m *Map[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	if f := m.loadFaults(); f != nil {
+		m.delay(context.Background(), f)
+	}
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if dst := m.forwardTo(shard); dst != nil {
+		dst.SetWithTTL(key, value, ttl)
+		shard.drop(key)
+		return
+	}
+	shard.store(key, value)
+	shard.expire(key, ttl)
+}
+
+// Returns the remaining lifetime of the key, 0 if it has no expiration. ok
+// is false if the key is missing or expired.
+func (m *Map[K, V]) TTL(key K) (remaining time.Duration, ok bool) {
+	shard := m.locate(key)
+	shard.RLock()
+	_, ok = shard.lookup(key)
+	e, hasExpiry := shard.expires[key]
+	shard.RUnlock()
+	if !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.TTL(key)
+		}
+		return 0, false
+	}
+	if !hasExpiry {
+		return 0, true
+	}
+	return time.Duration(e.at - time.Now().UnixNano()), true
+}
+
+// Touch gives the key a new TTL from now on, keeping actively used entries
+// alive. A non-positive ttl removes the expiration. Returns false if the key
+// is missing or expired.
+func (m *Map[K, V]) Touch(key K, ttl time.Duration) bool {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if _, ok := shard.lookup(key); !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.Touch(key, ttl)
+		}
+		return false
+	}
+	shard.expire(key, ttl)
+	return true
+}
+
+// Removes all expired entries, one shard at a time, and returns their number.
+// Soft-deleted items past the soft-delete window are dropped too, uncounted.
+func (m *Map[K, V]) DeleteExpired() int {
+	var hook func(key K, value V)
+	if p := m.onExpire.Load(); p != nil {
+		hook = *p
+	}
+	count := 0
+	var expired []Entry[K, V]
+	for _, shard := range m.shards() {
+		shard.Lock()
+		now := time.Now().UnixNano()
+		n := 0
+		for key, e := range shard.expires {
+			if !e.expired(now) {
+				continue
+			}
+			if hook != nil {
+				expired = append(expired, Entry[K, V]{key, shard.items[key]})
+			}
+			shard.remove(key)
+			n++
+		}
+		m.reapTombstones(shard, now)
+		shard.Unlock()
+		m.countExpired(shard, n)
+		count += n
+		for _, entry := range expired {
+			hook(entry.Key, entry.Value)
+		}
+		expired = expired[:0]
+	}
+	return count
+}
+
+// OnExpire sets a hook called with each entry removed because it expired,
+// by DeleteExpired, a janitor, or a Get finding it expired. The hook is
+// called without any lock held, so it may release resources held by the
+// value or use the map. Expired entries that are overwritten or deleted
+// before being read or swept are not reported. A nil hook removes it.
+func (m *Map[K, V]) OnExpire(hook func(key K, value V)) {
+	if hook == nil {
+		m.onExpire.Store(nil)
+		return
+	}
+	m.onExpire.Store(&hook)
+}
+
+// Removes the key if it is expired and calls hook with it
+func (m *Map[K, V]) reap(shard *shard[K, V], key K, hook func(key K, value V)) {
+	shard.Lock()
+	value, present := shard.items[key]
+	e, hasExpiry := shard.expires[key]
+	expired := present && hasExpiry && e.expired(time.Now().UnixNano())
+	if expired {
+		shard.remove(key)
+	}
+	shard.Unlock()
+	if expired {
+		m.countExpired(shard, 1)
+		hook(key, value)
+	}
+}
+
+// Janitor removes expired entries of a map in the background, as started by
+// StartJanitor.
+type Janitor struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// StartJanitor sweeps the expired entries of the map every interval, one
+// shard at a time so writers are only blocked on a single shard. Sweeps are
+// delayed while maintenance is paused. An interval below a millisecond is
+// raised to one. The janitor must be closed when no longer used.
+func (m *Map[K, V]) StartJanitor(interval time.Duration) *Janitor {
+	j := &Janitor{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if !m.inflight.begin() {
+		close(j.done)
+		return j
+	}
+
+	go func() {
+		defer m.inflight.end()
+		defer close(j.done)
+		ticker := time.NewTicker(clampInterval(interval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-j.stop:
+				return
+			}
+			select {
+			case <-m.maintenance.allowed():
+				m.DeleteExpired()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+	return j
+}
+
+// Raises the interval of a background task to at least a millisecond, as
+// time.NewTicker panics on non-positive intervals
+func clampInterval(interval time.Duration) time.Duration {
+	return max(interval, time.Millisecond)
+}
+
+// Stops the janitor
+func (j *Janitor) Close() {
+	j.stopOnce.Do(func() { close(j.stop) })
+	<-j.done
+}
+
+// SetSlidingExpiration makes Get renew the TTL of the entries it reads, so
+// they expire only after going unread for their whole TTL, as sessions do.
+// With sliding expiration Get takes the shard write lock instead of the read
+// lock. Entries without a TTL are not affected.
+func (m *Map[K, V]) SetSlidingExpiration(sliding bool) {
+	m.sliding.Store(sliding)
+}
+
+// WithTTL gives the values written from then on by Set and the other writes
+// without an explicit TTL an expiration after ttl, for caches whose entries
+// all share a lifetime. SetWithTTL and Touch override it per key. A
+// non-positive ttl stops expiring new writes. Returns the map for chaining.
+func (m *Map[K, V]) WithTTL(ttl time.Duration) *Map[K, V] {
+	for _, shard := range m.shards() {
+		shard.Lock()
+		shard.ttl = ttl
+		shard.Unlock()
+	}
+	return m
+}
+
+// Renews the TTL of a key that has one, must be called with the write lock held
+func (shard *shard[K, V]) slide(key K) {
+	if e, ok := shard.expires[key]; ok {
+		shard.setExpiry(key, newExpiry(e.ttl))
+	}
+}
+
+// Sets a new TTL on each of the given keys that exists, grouping keys by shard
+// so every shard is locked only once. A non-positive ttl removes the expiration.
+// Returns the number of keys updated.
+func (m *Map[K, V]) ExpireMany(keys []K, ttl time.Duration) int {
+	count := 0
+	shards, groups := m.groupByShard(keys)
+	for i, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		shard := shards[i]
+		shard.Lock()
+		for _, key := range group {
+			if _, ok := shard.lookup(key); !ok {
+				if dst := m.forwardTo(shard); dst != nil {
+					count += dst.ExpireMany([]K{key}, ttl)
+				}
+				continue
+			}
+			shard.expire(key, ttl)
+			count++
+		}
+		shard.Unlock()
+	}
+	return count
+}
+
+// RefreshIfStale revalidates an entry whose TTL was last set more than maxAge
+// ago. validate is called without holding any lock and, if it returns true,
+// the entry gets its TTL again from now on, avoiding a full reload.
+// Returns false if the key is missing or validation fails, in which case the
+// caller should reload the value. Entries without a TTL never become stale.
+func (m *Map[K, V]) RefreshIfStale(key K, maxAge time.Duration, validate func(value V) bool) bool {
+	shard := m.locate(key)
+	shard.RLock()
+	value, ok := shard.lookup(key)
+	e, hasExpiry := shard.expires[key]
+	shard.RUnlock()
+
+	if !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.RefreshIfStale(key, maxAge, validate)
+		}
+		return false
+	}
+	if !hasExpiry || time.Since(time.Unix(0, e.at-int64(e.ttl))) < maxAge {
+		return true
+	}
+	if !validate(value) {
+		return false
+	}
+
+	shard.Lock()
+	if current, has := shard.expires[key]; has && current == e {
+		shard.setExpiry(key, newExpiry(e.ttl))
+		shard.Unlock()
+		return true
+	}
+	// The entry was written, removed or moved while validating.
+	_, ok = shard.lookup(key)
+	dst := m.forwardTo(shard)
+	shard.Unlock()
+	if !ok && dst != nil {
+		return dst.RefreshIfStale(key, maxAge, validate)
+	}
+	return ok
+}
@@ -0,0 +1,172 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_SetWithTTL(t *testing.T) {
+	m := New64()
+	m.SetWithTTL(1, 1, time.Millisecond)
+	m.SetWithTTL(2, 2, 0)
+	if !m.Has(1) {
+		t.Error("entry should be visible before its TTL")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if m.Has(1) || !m.Has(2) {
+		t.Error("only the entry with a TTL should expire")
+	}
+	if m.DeleteExpired() != 1 || m.Size() != 1 {
+		t.Error("DeleteExpired should remove the expired entry", m.Size())
+	}
+}
+
+// Returns the number of items stored in the map, including expired ones
+func storedItems(m *SyncMap64) int {
+	n := 0
+	for _, size := range m.ShardSizes() {
+		n += size
+	}
+	return n
+}
+
+func Test_StartJanitor(t *testing.T) {
+	m := New64()
+	j := m.StartJanitor(2 * time.Millisecond)
+	defer j.Close()
+
+	m.PauseMaintenance()
+	m.SetWithTTL(1, 1, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if storedItems(m) != 1 {
+		t.Error("janitor should not sweep while maintenance is paused")
+	}
+	m.ResumeMaintenance()
+
+	deadline := time.Now().Add(time.Second)
+	for storedItems(m) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if storedItems(m) != 0 {
+		t.Error("janitor should sweep expired entries", storedItems(m))
+	}
+}
+
+func Test_StartJanitorZeroInterval(t *testing.T) {
+	m := New64()
+	j := m.StartJanitor(0)
+	defer j.Close()
+	m.SetWithTTL(1, 1, time.Nanosecond)
+	deadline := time.Now().Add(time.Second)
+	for storedItems(m) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if storedItems(m) != 0 {
+		t.Error("a non-positive interval should sweep every millisecond", storedItems(m))
+	}
+}
+
+func Test_TTLTouch(t *testing.T) {
+	m := New64()
+	if _, ok := m.TTL(1); ok || m.Touch(1, time.Second) {
+		t.Error("TTL and Touch should fail for missing key")
+	}
+	m.Set(1, 1)
+	if ttl, ok := m.TTL(1); !ok || ttl != 0 {
+		t.Error("TTL should be 0 without expiration", ttl)
+	}
+
+	m.SetWithTTL(1, 1, time.Minute)
+	if ttl, ok := m.TTL(1); !ok || ttl <= 59*time.Second || ttl > time.Minute {
+		t.Error("TTL should return the remaining lifetime", ttl)
+	}
+	if !m.Touch(1, time.Hour) {
+		t.Error("Touch should succeed for existing key")
+	}
+	if ttl, _ := m.TTL(1); ttl <= time.Minute {
+		t.Error("Touch should extend the lifetime", ttl)
+	}
+
+	m.Touch(1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := m.TTL(1); ok || m.Touch(1, time.Hour) {
+		t.Error("Touch should not revive an expired key")
+	}
+}
+
+func Test_SlidingExpiration(t *testing.T) {
+	m := New64()
+	m.SetSlidingExpiration(true)
+	m.SetWithTTL(1, 1, 50*time.Millisecond)
+	m.SetWithTTL(2, 2, 50*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		time.Sleep(15 * time.Millisecond)
+		if !m.Has(1) {
+			t.Fatal("reading an entry should keep it alive", i)
+		}
+	}
+	if _, ok := m.TTL(2); ok {
+		t.Error("an entry that is not read should expire")
+	}
+
+	m.SetSlidingExpiration(false)
+	time.Sleep(10 * time.Millisecond)
+	m.Get(1)
+	time.Sleep(45 * time.Millisecond)
+	if m.Has(1) {
+		t.Error("Get should not renew the TTL without sliding expiration")
+	}
+}
+
+func Test_OnExpire(t *testing.T) {
+	m := New64()
+	var expired []uint64
+	m.OnExpire(func(key uint64, value interface{}) {
+		if m.Has(key) {
+			t.Error("expired entry should be removed before the hook")
+		}
+		expired = append(expired, key)
+	})
+
+	m.SetWithTTL(1, 1, time.Millisecond)
+	m.SetWithTTL(2, 2, time.Millisecond)
+	m.SetWithTTL(3, 3, time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	if m.Has(1) {
+		t.Error("entry should be expired")
+	}
+	if len(expired) != 1 || expired[0] != 1 {
+		t.Error("Get should report the expired entry", expired)
+	}
+	if m.DeleteExpired() != 1 || len(expired) != 2 || expired[1] != 2 {
+		t.Error("DeleteExpired should report the expired entries", expired)
+	}
+
+	m.OnExpire(nil)
+	m.SetWithTTL(4, 4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	m.DeleteExpired()
+	if len(expired) != 2 {
+		t.Error("removed hook should not be called", expired)
+	}
+}
+
+func Test_WithTTL(t *testing.T) {
+	m := New64().WithTTL(time.Millisecond)
+	m.Set(1, 1)
+	m.SetWithTTL(2, 2, time.Hour)
+	if ttl, ok := m.TTL(1); !ok || ttl <= 0 {
+		t.Error("Set should apply the default TTL", ttl, ok)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if m.Has(1) || !m.Has(2) {
+		t.Error("only the entry with the default TTL should expire")
+	}
+
+	m.WithTTL(0)
+	m.Set(3, 3)
+	if ttl, ok := m.TTL(3); !ok || ttl != 0 {
+		t.Error("a non-positive default TTL should stop expiring writes", ttl, ok)
+	}
+}
@@ -0,0 +1,69 @@
+package syncmap
+
+import "fmt"
+
+// Returns the version of a present key, giving it a new one if it was
+// written since the last. Versions are never reused within a shard. Must be
+// called with the write lock held.
+func (shard *shard[K, V]) versionOf(key K) uint64 {
+	if version, ok := shard.versions[key]; ok {
+		return version
+	}
+	if shard.versions == nil {
+		shard.versions = make(map[K]uint64)
+	}
+	shard.version++
+	shard.versions[key] = shard.version
+	return shard.version
+}
+
+// GetVersioned retrieves a value along with its version, for a later
+// CompareVersionAndSwap. The version changes whenever the key is written or
+// deleted. Versions are given out by the shards of the map, so those read
+// before Migrate or Reshard no longer match after it.
+func (m *Map[K, V]) GetVersioned(key K) (value V, version uint64, ok bool) {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	value, ok = shard.lookup(key)
+	if !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.GetVersioned(key)
+		}
+		return value, 0, false
+	}
+	return value, shard.versionOf(key), true
+}
+
+// CompareVersionAndSwap sets the value of key only if its version is still
+// version, as returned by GetVersioned, under a single shard lock. Version 0
+// requires the key to be absent. Returns the new version, or an error
+// wrapping ErrVersionMismatch if the key was written or deleted since.
+// Updating an entry keeps its expiration, and a new entry gets the default
+// TTL of the map.
+func (m *Map[K, V]) CompareVersionAndSwap(key K, version uint64, value V) (uint64, error) {
+	shard := m.locate(key)
+	shard.Lock()
+	defer shard.Unlock()
+	_, ok := shard.lookup(key)
+	if !ok {
+		if dst := m.forwardTo(shard); dst != nil {
+			return dst.CompareVersionAndSwap(key, version, value)
+		}
+	}
+	current := uint64(0)
+	if ok {
+		current = shard.versionOf(key)
+	}
+	if current != version {
+		return 0, fmt.Errorf("%w: key %v has version %d, not %d", ErrVersionMismatch, key, current, version)
+	}
+	if ok {
+		shard.put(key, value)
+	} else {
+		// Drop an expired entry so its expiration is not kept.
+		shard.remove(key)
+		shard.store(key, value)
+	}
+	return shard.versionOf(key), nil
+}
@@ -0,0 +1,51 @@
+package syncmap
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_CompareVersionAndSwap(t *testing.T) {
+	m := New64()
+	if _, _, ok := m.GetVersioned(1); ok {
+		t.Error("GetVersioned should miss a missing key")
+	}
+	v1, err := m.CompareVersionAndSwap(1, 0, "a")
+	if err != nil || v1 == 0 {
+		t.Fatal("version 0 should create a missing key", v1, err)
+	}
+	if _, err := m.CompareVersionAndSwap(1, 0, "b"); !errors.Is(err, ErrVersionMismatch) {
+		t.Error("version 0 should fail for a present key", err)
+	}
+
+	value, version, ok := m.GetVersioned(1)
+	if !ok || value != "a" || version != v1 {
+		t.Error("GetVersioned should return the version of the last write", value, version, v1)
+	}
+	m.Set(1, "a")
+	if _, err := m.CompareVersionAndSwap(1, version, "c"); !errors.Is(err, ErrVersionMismatch) {
+		t.Error("a write should change the version, even with the same value", err)
+	}
+	_, version, _ = m.GetVersioned(1)
+	if version == v1 {
+		t.Error("versions should not be reused")
+	}
+	m.ExpireMany([]uint64{1}, time.Hour)
+	if _, err := m.CompareVersionAndSwap(1, version, "c"); err != nil {
+		t.Error("a matching version should swap", err)
+	}
+	if v, _ := m.Get(1); v != "c" {
+		t.Error("the swapped value should be stored", v)
+	}
+	if ttl, _ := m.TTL(1); ttl < 59*time.Minute {
+		t.Error("swapping should keep the expiration", ttl)
+	}
+
+	_, version, _ = m.GetVersioned(1)
+	m.Delete(1)
+	m.Set(1, "d")
+	if _, err := m.CompareVersionAndSwap(1, version, "e"); !errors.Is(err, ErrVersionMismatch) {
+		t.Error("deleting and setting again should change the version", err)
+	}
+}
@@ -0,0 +1,137 @@
+package syncmap
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// A write-ahead log of the writes to a map
+type wal[K comparable, V any] struct {
+	mu  sync.Mutex
+	enc *gob.Encoder
+	seq uint64
+	err error
+}
+
+// walRecord is the state of a key after a write, as appended to the log.
+type walRecord[K comparable, V any] struct {
+	Seq     uint64
+	Op      EventOp // EventSet or EventDelete
+	Key     K
+	Value   V             // unset for EventDelete
+	Expires int64         // unix nanoseconds, 0 without expiration
+	TTL     time.Duration // the TTL the expiration was set with
+}
+
+// WithWAL makes every write to the map append the resulting state of the
+// written key to w, encoded with gob, so the map can be rebuilt with Replay
+// after a crash. This covers all writes, including compound ones such as
+// Swap or Update, transactions, Flush, evictions and the removal of expired
+// entries, along with the expiration of each key; with sliding expiration,
+// every Get renewing a TTL is logged. A key written several times under one
+// shard lock is logged once, with the shard lock held, so the log order of
+// the writes to a key is the order they were applied in, and a slow w slows
+// down writers. Once writing to w fails, logging stops and WALErr returns
+// the error. A nil w disables the log. Returns the map for chaining.
+//
+// Each call starts a new gob stream, which cannot be decoded after the end
+// of an earlier one: w must be a new or truncated file, not one appended to.
+// To keep the log of a previous run, Replay it and log to a new file, for
+// example after saving a snapshot with SaveTo, and replay the files in
+// order after the next crash.
+func (m *Map[K, V]) WithWAL(w io.Writer) *Map[K, V] {
+	if w == nil {
+		m.useWAL(nil)
+	} else {
+		m.useWAL(&wal[K, V]{enc: gob.NewEncoder(w)})
+	}
+	return m
+}
+
+// Makes the map and its shards log to l
+func (m *Map[K, V]) useWAL(l *wal[K, V]) {
+	m.wal.Store(l)
+	for _, shard := range m.shards() {
+		shard.Lock()
+		shard.wal = l
+		shard.Unlock()
+	}
+}
+
+// Returns the error that stopped the write-ahead log, nil if none
+func (m *Map[K, V]) WALErr() error {
+	l := m.wal.Load()
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Marks a key as written, to log its state when the shard is unlocked. Must
+// be called with the write lock held.
+func (shard *shard[K, V]) touch(key K) {
+	if shard.wal == nil {
+		return
+	}
+	if shard.touched == nil {
+		shard.touched = make(map[K]struct{})
+	}
+	shard.touched[key] = struct{}{}
+}
+
+// Appends the state of the keys written since the shard was locked to its
+// log, must be called with the write lock held
+func (shard *shard[K, V]) logTouched() {
+	l := shard.wal
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key := range shard.touched {
+		if l.err != nil {
+			break
+		}
+		l.seq++
+		r := walRecord[K, V]{Seq: l.seq, Op: EventDelete, Key: key}
+		if value, ok := shard.items[key]; ok {
+			r.Op, r.Value = EventSet, value
+			if e, ok := shard.expires[key]; ok {
+				r.Expires, r.TTL = e.at, e.ttl
+			}
+		}
+		l.err = l.enc.Encode(r)
+	}
+	clear(shard.touched)
+}
+
+// Replay applies the records of a log written by WithWAL to the map, in
+// order, and returns the number applied. Keys keep the expiration they were
+// logged with, and those expired since are deleted. It stops without error
+// at the end of r. A log whose last record was cut short by a crash returns
+// an error wrapping io.ErrUnexpectedEOF after applying the complete records.
+func (m *Map[K, V]) Replay(r io.Reader) (applied int, err error) {
+	dec := gob.NewDecoder(r)
+	for {
+		var rec walRecord[K, V]
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return applied, nil
+			}
+			return applied, err
+		}
+		switch {
+		case rec.Op == EventSet && rec.Expires == 0:
+			m.Set(rec.Key, rec.Value)
+		case rec.Op == EventSet && rec.Expires > time.Now().UnixNano():
+			m.restore(rec.Key, rec.Value, expiry{at: rec.Expires, ttl: rec.TTL})
+		case rec.Op == EventSet || rec.Op == EventDelete:
+			m.Delete(rec.Key)
+		default:
+			return applied, errors.New("syncmap: unknown event op in log")
+		}
+		applied++
+	}
+}
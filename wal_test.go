@@ -0,0 +1,115 @@
+package syncmap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func Test_WAL(t *testing.T) {
+	var log bytes.Buffer
+	m := New64().WithWAL(&log)
+	for i := uint64(0); i < 10; i++ {
+		m.Set(i, int(i))
+	}
+	m.Delete(3)
+	m.Set(4, "four")
+	if err := m.WALErr(); err != nil {
+		t.Fatal(err)
+	}
+	data := log.Bytes()
+
+	restored := New64()
+	applied, err := restored.Replay(bytes.NewReader(data))
+	if err != nil || applied != 12 {
+		t.Error("replay should apply every logged write", applied, err)
+	}
+	if restored.Size() != 9 || restored.Has(3) {
+		t.Error("replay should rebuild the map", restored.Size())
+	}
+	if v, _ := restored.Get(4); v != "four" {
+		t.Error("replay should apply writes in order", v)
+	}
+
+	applied, err = New64().Replay(bytes.NewReader(data[:len(data)-3]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) || applied != 11 {
+		t.Error("replay of a torn log should apply the complete events", applied, err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func Test_WALErr(t *testing.T) {
+	m := New64().WithWAL(failingWriter{})
+	m.Set(1, 1)
+	if !errors.Is(m.WALErr(), io.ErrClosedPipe) {
+		t.Error("WALErr should return the write error", m.WALErr())
+	}
+	if v, _ := m.Get(1); v != 1 {
+		t.Error("a failing log should not fail writes")
+	}
+}
+
+func Test_WALAllWrites(t *testing.T) {
+	var log bytes.Buffer
+	m := NewWithCapacity64(64).WithWAL(&log)
+	for i := uint64(0); i < 100; i++ {
+		m.Set(i, int(i))
+	}
+	m.SetWithTTL(200, "ttl", time.Hour)
+	m.SetWithTTL(201, "expired", time.Nanosecond)
+	m.Swap(99, "swapped")
+	m.GetAndDelete(98)
+	m.CompareAndDelete(97, 97)
+	m.Update(96, func(old interface{}, exists bool) (interface{}, bool) { return "updated", true })
+	m.Upsert(95, 1, func(existing, incoming interface{}) interface{} { return "merged" })
+	m.GetOrSet(300, "new")
+	m.Pop()
+	time.Sleep(time.Millisecond)
+	m.DeleteExpired()
+	if err := m.WALErr(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New64()
+	if _, err := restored.Replay(bytes.NewReader(log.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Equal(m, nil) {
+		t.Error("replay should rebuild every write, including evictions", restored.Size(), m.Size())
+	}
+	// Key 200 may have been evicted since, depending on the shard it hashed to.
+	if _, ok := m.TTL(200); ok {
+		if ttl, _ := restored.TTL(200); ttl < 59*time.Minute {
+			t.Error("replay should keep expirations", ttl)
+		}
+	}
+
+	// A flush is logged as the deletion of every key.
+	m.Flush()
+	if _, err := restored.Replay(bytes.NewReader(log.Bytes())); err != nil || restored.Size() != 0 {
+		t.Error("replay should apply Flush", restored.Size(), err)
+	}
+}
+
+func Test_WALReshard(t *testing.T) {
+	var log bytes.Buffer
+	m := NewWithShard64(4).WithWAL(&log)
+	for i := uint64(0); i < 1000; i++ {
+		m.Set(i, int(i))
+	}
+	if err := m.Reshard(16); err != nil {
+		t.Fatal(err)
+	}
+	m.Delete(1)
+
+	restored := New64()
+	applied, err := restored.Replay(bytes.NewReader(log.Bytes()))
+	if err != nil || applied != 1001 || !restored.Equal(m, nil) {
+		t.Error("moving keys to new shards should not be logged", applied, err)
+	}
+}
@@ -0,0 +1,97 @@
+package syncmap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Loader loads entries into a map during warm-up by calling set for each of
+// them. It may read from a snapshot, a backing store or anything else.
+type Loader[K comparable, V any] func(set func(key K, value V)) error
+
+// Warmup tracks loaders filling a Map at startup. Ready is closed once
+// every loader has returned, so readiness probes can wait for a hot cache.
+type Warmup struct {
+	ready   chan struct{}
+	total   int
+	done    int32
+	loaded  int64
+	started time.Time
+
+	mu  sync.Mutex
+	err error
+}
+
+// WarmupProgress is a snapshot of the progress of a Warmup.
+type WarmupProgress struct {
+	Loaded      int64         // entries loaded so far
+	LoadersDone int           // loaders that have returned
+	Loaders     int           // total number of loaders
+	Elapsed     time.Duration // time since the warm-up started
+}
+
+// Warmup runs the given loaders concurrently, each in its own goroutine, and
+// returns immediately. Entries are set in the map as they are loaded, so the
+// map is usable while warming up.
+func (m *Map[K, V]) Warmup(loaders ...Loader[K, V]) *Warmup {
+	w := &Warmup{
+		ready:   make(chan struct{}),
+		total:   len(loaders),
+		started: time.Now(),
+	}
+	if !m.inflight.begin() {
+		w.err = ErrMapClosed
+		close(w.ready)
+		return w
+	}
+	set := func(key K, value V) {
+		m.Set(key, value)
+		atomic.AddInt64(&w.loaded, 1)
+	}
+
+	var wg sync.WaitGroup
+	for _, load := range loaders {
+		wg.Add(1)
+		go func(load Loader[K, V]) {
+			defer wg.Done()
+			err := load(set)
+			atomic.AddInt32(&w.done, 1)
+			if err != nil {
+				w.mu.Lock()
+				if w.err == nil {
+					w.err = err
+				}
+				w.mu.Unlock()
+			}
+		}(load)
+	}
+	go func() {
+		defer m.inflight.end()
+		wg.Wait()
+		close(w.ready)
+	}()
+	return w
+}
+
+// Returns a channel that is closed when all loaders have returned
+func (w *Warmup) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// Returns the first error returned by a loader, if any
+func (w *Warmup) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Returns the current progress of the warm-up
+func (w *Warmup) Progress() WarmupProgress {
+	return WarmupProgress{
+		Loaded:      atomic.LoadInt64(&w.loaded),
+		LoadersDone: int(atomic.LoadInt32(&w.done)),
+		Loaders:     w.total,
+		Elapsed:     time.Since(w.started),
+	}
+}
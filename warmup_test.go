@@ -0,0 +1,46 @@
+package syncmap
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Warmup64(t *testing.T) {
+	m := New64()
+	release := make(chan struct{})
+	loadErr := errors.New("backing store unavailable")
+
+	w := m.Warmup(
+		func(set func(uint64, interface{})) error {
+			for i := 0; i < 10; i++ {
+				set(uint64(i), i)
+			}
+			return nil
+		},
+		func(set func(uint64, interface{})) error {
+			<-release
+			set(100, 100)
+			return loadErr
+		},
+	)
+
+	select {
+	case <-w.Ready():
+		t.Error("Ready should not be closed before all loaders return")
+	default:
+	}
+
+	close(release)
+	<-w.Ready()
+
+	p := w.Progress()
+	if p.Loaded != 11 || p.LoadersDone != 2 || p.Loaders != 2 {
+		t.Error("Progress should report every loaded entry", p)
+	}
+	if m.Size() != 11 {
+		t.Error("Warmup should set every loaded entry")
+	}
+	if !errors.Is(w.Err(), loadErr) {
+		t.Error("Err should return the loader error")
+	}
+}